@@ -0,0 +1,44 @@
+package deck_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	"flashcards/deck"
+)
+
+func TestPublicAPI(t *testing.T) {
+	fc := deck.NewFlashcards()
+	if err := fc.Add(deck.Flashcard{Term: "cat", Definition: "кот"}); err != nil {
+		t.Fatalf("Add() = %v", err)
+	}
+	if err := fc.Add(deck.Flashcard{Term: "dog", Definition: "собака", Tags: []string{"pets"}}); err != nil {
+		t.Fatalf("Add() = %v", err)
+	}
+	fc.IncrementMistakes("cat")
+
+	if definition, exists := fc.FindDefinitionByTerm("cat"); !exists || definition != "кот" {
+		t.Errorf("FindDefinitionByTerm(cat) = %q, %v", definition, exists)
+	}
+	if term, exists := fc.FindTermByDefinition("собака"); !exists || term != "dog" {
+		t.Errorf("FindTermByDefinition(собака) = %q, %v", term, exists)
+	}
+	if hardest := fc.HardestCards(); len(hardest) != 1 || hardest[0].Term != "cat" {
+		t.Errorf("HardestCards() = %v", hardest)
+	}
+
+	filename := filepath.Join(t.TempDir(), "deck.csv")
+	if n, err := fc.WriteFile(filename); err != nil || n != 2 {
+		t.Fatalf("WriteFile() = %d, %v", n, err)
+	}
+	loaded := deck.NewFlashcards()
+	if n, err := loaded.ReadFile(filename); err != nil || n != 2 {
+		t.Fatalf("ReadFile() = %d, %v", n, err)
+	}
+	if cat, _ := loaded.GetByTerm("cat"); cat.Mistakes != 1 {
+		t.Errorf("loaded cat = %+v", cat)
+	}
+	if !loaded.RemoveByTerm("cat") || loaded.Len() != 1 {
+		t.Errorf("RemoveByTerm(cat) left %d cards", loaded.Len())
+	}
+}
@@ -0,0 +1,727 @@
+package deck
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/gob"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var httpClient = &http.Client{Timeout: 10 * time.Second}
+
+func parseURL(location string) (*url.URL, bool) {
+	u, err := url.Parse(location)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return nil, false
+	}
+	return u, true
+}
+
+func sourceExt(location string) string {
+	if u, ok := parseURL(location); ok {
+		return strings.ToLower(path.Ext(u.Path))
+	}
+	return strings.ToLower(filepath.Ext(location))
+}
+
+const StdStream = "-"
+
+func (fc *Flashcards) readSource(location string) (io.ReadCloser, error) {
+	if location == StdStream {
+		return io.NopCloser(fc.stdin), nil
+	}
+	if _, ok := parseURL(location); !ok {
+		return os.Open(location)
+	}
+
+	resp, err := httpClient.Get(location)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("%s responded with %s", location, resp.Status)
+	}
+	return resp.Body, nil
+}
+
+func formatLastSeen(lastSeen time.Time) string {
+	if lastSeen.IsZero() {
+		return ""
+	}
+	return lastSeen.Format(time.RFC3339)
+}
+
+func (fc *Flashcards) writeDestination(filename string, write func(w io.Writer) error) error {
+	if filename == StdStream {
+		return write(fc.stdout)
+	}
+	return writeAtomically(filename, write)
+}
+
+func writeAtomically(filename string, write func(w io.Writer) error) error {
+	tmp, err := os.CreateTemp(filepath.Dir(filename), "."+filepath.Base(filename)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := write(tmp); err != nil {
+		tmp.Close()
+		return err
+	}
+	mode := os.FileMode(0644)
+	if info, err := os.Stat(filename); err == nil {
+		mode = info.Mode().Perm()
+	}
+	if err := tmp.Chmod(mode); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), filename)
+}
+
+var csvColumns = []string{"term", "definition", "mistakes", "tags", "lastSeen", "correct", "streak"}
+
+func (fc *Flashcards) writeCSVFile(filename string, flashcards []Flashcard) error {
+	return fc.writeDestination(filename, func(w io.Writer) error {
+		writer := csv.NewWriter(w)
+		writer.Comma = fc.csvDelimiter()
+
+		if fc.header {
+			if err := writer.Write(csvColumns); err != nil {
+				return err
+			}
+		}
+		for _, flashcard := range flashcards {
+			record := []string{
+				flashcard.Term,
+				flashcard.Definition,
+				strconv.Itoa(flashcard.Mistakes),
+				strings.Join(flashcard.Tags, ","),
+				formatLastSeen(flashcard.LastSeen),
+				strconv.Itoa(flashcard.Correct),
+				strconv.Itoa(flashcard.Streak),
+			}
+			if err := writer.Write(record); err != nil {
+				return err
+			}
+		}
+		writer.Flush()
+		return writer.Error()
+	})
+}
+
+func (fc *Flashcards) WriteCSV(filename string) (int, error) {
+	flashcards := fc.All()
+	if err := fc.writeCSVFile(filename, flashcards); err != nil {
+		return 0, err
+	}
+	return len(flashcards), nil
+}
+
+func (fc *Flashcards) WriteReversedCSV(filename string) (int, error) {
+	flashcards := fc.All()
+	for i, flashcard := range flashcards {
+		flashcards[i].Term, flashcards[i].Definition = flashcard.Definition, flashcard.Term
+	}
+	if err := fc.writeCSVFile(filename, flashcards); err != nil {
+		return 0, err
+	}
+	return len(flashcards), nil
+}
+
+func (fc *Flashcards) SplitCSV(base string, parts int) ([]string, error) {
+	flashcards := fc.All()
+	parts = min(parts, len(flashcards))
+	if parts < 1 {
+		return nil, nil
+	}
+
+	ext := filepath.Ext(base)
+	stem := strings.TrimSuffix(base, ext)
+	if ext == "" {
+		ext = ".csv"
+	}
+	filenames := make([]string, 0, parts)
+	start := 0
+	for i := 0; i < parts; i++ {
+		size := len(flashcards) / parts
+		if i < len(flashcards)%parts {
+			size++
+		}
+		filename := fmt.Sprintf("%s_%d%s", stem, i+1, ext)
+		if err := fc.writeCSVFile(filename, flashcards[start:start+size]); err != nil {
+			return filenames, err
+		}
+		filenames = append(filenames, filename)
+		start += size
+	}
+	return filenames, nil
+}
+
+func (fc *Flashcards) WriteHardestCSV(filename string) (int, error) {
+	hardestCards := fc.HardestCards()
+	if len(hardestCards) == 0 {
+		return 0, nil
+	}
+	if err := fc.writeCSVFile(filename, hardestCards); err != nil {
+		return 0, err
+	}
+	return len(hardestCards), nil
+}
+
+func csvHeader(record []string) (map[string]int, bool) {
+	columns := make(map[string]int, len(record))
+	for i, name := range record {
+		for _, column := range csvColumns {
+			if strings.EqualFold(strings.TrimSpace(name), column) {
+				columns[column] = i
+			}
+		}
+	}
+	_, hasTerm := columns["term"]
+	_, hasDefinition := columns["definition"]
+	return columns, hasTerm && hasDefinition
+}
+
+func (fc *Flashcards) loadCSV(location string) (flashcards []Flashcard, skipped []SkippedRecord, err error) {
+	source, err := fc.readSource(location)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer source.Close()
+
+	reader := csv.NewReader(source)
+	reader.Comma = fc.csvDelimiter()
+	reader.FieldsPerRecord = -1
+	if fc.comments && reader.Comma != '#' {
+		reader.Comment = '#'
+	}
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	columns := make(map[string]int, len(csvColumns))
+	for i, column := range csvColumns {
+		columns[column] = i
+	}
+	first := 0
+	if len(records) > 0 {
+		if header, ok := csvHeader(records[0]); ok {
+			columns = header
+			first = 1
+		}
+	}
+	field := func(record []string, column string) (string, bool) {
+		i, ok := columns[column]
+		if !ok || i >= len(record) {
+			return "", false
+		}
+		return record[i], true
+	}
+
+	flashcards = make([]Flashcard, 0, len(records))
+	for i := first; i < len(records); i++ {
+		record := records[i]
+		term, hasTerm := field(record, "term")
+		definition, hasDefinition := field(record, "definition")
+		if !hasTerm || !hasDefinition {
+			skipped = append(skipped, SkippedRecord{Record: i + 1, Err: ErrTooFewFields})
+			continue
+		}
+		if err := Validate(term, definition); err != nil {
+			skipped = append(skipped, SkippedRecord{Record: i + 1, Err: err})
+			continue
+		}
+		loadedFlashcard := Flashcard{
+			Term:       term,
+			Definition: definition,
+		}
+		if mistakes, ok := field(record, "mistakes"); ok && mistakes != "" {
+			if loadedFlashcard.Mistakes, err = strconv.Atoi(mistakes); err != nil {
+				skipped = append(skipped, SkippedRecord{Record: i + 1, Err: ErrInvalidMistakes})
+				continue
+			}
+		}
+		if tags, ok := field(record, "tags"); ok {
+			loadedFlashcard.Tags = ParseTags(tags)
+		}
+		if lastSeen, ok := field(record, "lastSeen"); ok && lastSeen != "" {
+			if loadedFlashcard.LastSeen, err = time.Parse(time.RFC3339, lastSeen); err != nil {
+				skipped = append(skipped, SkippedRecord{Record: i + 1, Err: ErrInvalidLastSeen})
+				continue
+			}
+		}
+		if correct, ok := field(record, "correct"); ok && correct != "" {
+			if loadedFlashcard.Correct, err = strconv.Atoi(correct); err != nil {
+				skipped = append(skipped, SkippedRecord{Record: i + 1, Err: ErrInvalidCorrect})
+				continue
+			}
+		}
+		if streak, ok := field(record, "streak"); ok && streak != "" {
+			if loadedFlashcard.Streak, err = strconv.Atoi(streak); err != nil {
+				skipped = append(skipped, SkippedRecord{Record: i + 1, Err: ErrInvalidStreak})
+				continue
+			}
+		}
+		flashcards = append(flashcards, loadedFlashcard)
+	}
+	return flashcards, skipped, nil
+}
+
+func (fc *Flashcards) ReadCSV(location string) (int, error) {
+	flashcards, _, err := fc.loadCSV(location)
+	if err != nil {
+		return 0, err
+	}
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+	flashcards, _ = fc.capped(flashcards)
+	fc.load(flashcards)
+	return len(flashcards), nil
+}
+
+func (fc *Flashcards) WriteJSON(filename string) (int, error) {
+	fc.mu.RLock()
+	defer fc.mu.RUnlock()
+
+	flashcards := fc.all()
+	err := fc.writeDestination(filename, func(w io.Writer) error {
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(flashcards)
+	})
+	if err != nil {
+		return 0, err
+	}
+	return len(flashcards), nil
+}
+
+func (fc *Flashcards) loadJSON(location string) (flashcards []Flashcard, skipped []SkippedRecord, err error) {
+	source, err := fc.readSource(location)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer source.Close()
+
+	var decoded []Flashcard
+	if err := json.NewDecoder(source).Decode(&decoded); err != nil {
+		return nil, nil, err
+	}
+	for i, flashcard := range decoded {
+		if err := Validate(flashcard.Term, flashcard.Definition); err != nil {
+			skipped = append(skipped, SkippedRecord{Record: i + 1, Err: err})
+			continue
+		}
+		flashcards = append(flashcards, flashcard)
+	}
+	return flashcards, skipped, nil
+}
+
+func (fc *Flashcards) ReadJSON(location string) (int, error) {
+	flashcards, _, err := fc.loadJSON(location)
+	if err != nil {
+		return 0, err
+	}
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+	flashcards, _ = fc.capped(flashcards)
+	fc.load(flashcards)
+	return len(flashcards), nil
+}
+
+func (fc *Flashcards) WriteJSONL(filename string) (int, error) {
+	fc.mu.RLock()
+	defer fc.mu.RUnlock()
+
+	err := fc.writeDestination(filename, func(w io.Writer) error {
+		buffered := bufio.NewWriter(w)
+		encoder := json.NewEncoder(buffered)
+		for _, key := range fc.keys() {
+			if err := encoder.Encode(fc.elements[key]); err != nil {
+				return err
+			}
+		}
+		return buffered.Flush()
+	})
+	if err != nil {
+		return 0, err
+	}
+	return len(fc.elements), nil
+}
+
+func (fc *Flashcards) loadJSONL(location string) (flashcards []Flashcard, skipped []SkippedRecord, err error) {
+	source, err := fc.readSource(location)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer source.Close()
+
+	scanner := bufio.NewScanner(source)
+	scanner.Buffer(nil, 1<<20)
+	for line := 1; scanner.Scan(); line++ {
+		if strings.TrimSpace(scanner.Text()) == "" {
+			continue
+		}
+		var flashcard Flashcard
+		if err := json.Unmarshal(scanner.Bytes(), &flashcard); err != nil {
+			return nil, nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		if err := Validate(flashcard.Term, flashcard.Definition); err != nil {
+			skipped = append(skipped, SkippedRecord{Record: line, Err: err})
+			continue
+		}
+		flashcards = append(flashcards, flashcard)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, nil, err
+	}
+	return flashcards, skipped, nil
+}
+
+func (fc *Flashcards) ReadJSONL(location string) (int, error) {
+	result, err := fc.Import(location, false)
+	return result.Added, err
+}
+
+func (fc *Flashcards) WriteGob(filename string) (int, error) {
+	fc.mu.RLock()
+	defer fc.mu.RUnlock()
+
+	flashcards := fc.all()
+	err := fc.writeDestination(filename, func(w io.Writer) error {
+		return gob.NewEncoder(w).Encode(flashcards)
+	})
+	if err != nil {
+		return 0, err
+	}
+	return len(flashcards), nil
+}
+
+func (fc *Flashcards) loadGob(location string) (flashcards []Flashcard, skipped []SkippedRecord, err error) {
+	source, err := fc.readSource(location)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer source.Close()
+
+	var decoded []Flashcard
+	if err := gob.NewDecoder(source).Decode(&decoded); err != nil {
+		return nil, nil, err
+	}
+	for i, flashcard := range decoded {
+		if err := Validate(flashcard.Term, flashcard.Definition); err != nil {
+			skipped = append(skipped, SkippedRecord{Record: i + 1, Err: err})
+			continue
+		}
+		flashcards = append(flashcards, flashcard)
+	}
+	return flashcards, skipped, nil
+}
+
+func (fc *Flashcards) ReadGob(location string) (int, error) {
+	flashcards, _, err := fc.loadGob(location)
+	if err != nil {
+		return 0, err
+	}
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+	flashcards, _ = fc.capped(flashcards)
+	fc.load(flashcards)
+	return len(flashcards), nil
+}
+
+type CardReport struct {
+	Term     string  `json:"term"`
+	Mistakes int     `json:"mistakes"`
+	Correct  int     `json:"correct"`
+	Accuracy float64 `json:"accuracy"`
+	Streak   int     `json:"streak"`
+}
+
+type StatsReport struct {
+	Cards             int          `json:"cards"`
+	CardsWithMistakes int          `json:"cardsWithMistakes"`
+	TotalMistakes     int          `json:"totalMistakes"`
+	TotalCorrect      int          `json:"totalCorrect"`
+	AverageMistakes   float64      `json:"averageMistakes"`
+	Accuracy          float64      `json:"accuracy"`
+	Hardest           []string     `json:"hardest"`
+	PerCard           []CardReport `json:"perCard"`
+}
+
+func (fc *Flashcards) WriteStatsJSON(filename string) error {
+	fc.mu.RLock()
+	defer fc.mu.RUnlock()
+
+	stats := fc.stats()
+	report := StatsReport{
+		Cards:             stats.Cards,
+		CardsWithMistakes: stats.CardsWithMistakes,
+		TotalMistakes:     stats.TotalMistakes,
+		AverageMistakes:   stats.AverageMistakes,
+		Hardest:           []string{},
+		PerCard:           []CardReport{},
+	}
+	for _, flashcard := range fc.hardestCards() {
+		report.Hardest = append(report.Hardest, flashcard.Term)
+	}
+	for _, flashcard := range fc.all() {
+		report.TotalCorrect += flashcard.Correct
+		report.PerCard = append(report.PerCard, CardReport{
+			Term:     flashcard.Term,
+			Mistakes: flashcard.Mistakes,
+			Correct:  flashcard.Correct,
+			Accuracy: flashcard.Accuracy(),
+			Streak:   flashcard.Streak,
+		})
+	}
+	if answers := report.TotalCorrect + report.TotalMistakes; answers > 0 {
+		report.Accuracy = float64(report.TotalCorrect) / float64(answers)
+	}
+
+	return fc.writeDestination(filename, func(w io.Writer) error {
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(report)
+	})
+}
+
+func (fc *Flashcards) ReadQuizlet(location, termDelimiter, rowDelimiter string) (int, error) {
+	result, err := fc.ImportQuizlet(location, termDelimiter, rowDelimiter, false)
+	return result.Added, err
+}
+
+func (fc *Flashcards) ImportQuizlet(location, termDelimiter, rowDelimiter string, merge bool) (ImportResult, error) {
+	source, err := fc.readSource(location)
+	if err != nil {
+		return ImportResult{}, err
+	}
+	defer source.Close()
+
+	data, err := io.ReadAll(source)
+	if err != nil {
+		return ImportResult{}, err
+	}
+	var flashcards []Flashcard
+	var skipped []SkippedRecord
+	for i, row := range strings.Split(string(data), rowDelimiter) {
+		row = strings.TrimSuffix(row, "\r")
+		if strings.TrimSpace(row) == "" {
+			continue
+		}
+		term, definition, found := strings.Cut(row, termDelimiter)
+		if !found {
+			skipped = append(skipped, SkippedRecord{Record: i + 1, Err: ErrTooFewFields})
+			continue
+		}
+		if err := Validate(term, definition); err != nil {
+			skipped = append(skipped, SkippedRecord{Record: i + 1, Err: err})
+			continue
+		}
+		flashcards = append(flashcards, Flashcard{Term: term, Definition: definition})
+	}
+
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+	return fc.importCards(flashcards, skipped, merge), nil
+}
+
+func (fc *Flashcards) WriteAnkiTSV(filename string) (int, error) {
+	fc.mu.RLock()
+	defer fc.mu.RUnlock()
+
+	var sb strings.Builder
+	for _, key := range fc.keys() {
+		flashcard := fc.elements[key]
+		for _, field := range []string{flashcard.Term, flashcard.Definition} {
+			if strings.ContainsAny(field, "\t\r\n") {
+				return 0, fmt.Errorf("card \"%s\" contains a tab or a line break, which Anki can't import", flashcard.Term)
+			}
+		}
+		sb.WriteString(flashcard.Term + "\t" + flashcard.Definition + "\n")
+	}
+
+	err := fc.writeDestination(filename, func(w io.Writer) error {
+		_, err := io.WriteString(w, sb.String())
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+	return len(fc.elements), nil
+}
+
+var markdownCellReplacer = strings.NewReplacer("|", "\\|", "\r\n", "<br>", "\n", "<br>")
+
+func (fc *Flashcards) WriteMarkdown(filename string) (int, error) {
+	fc.mu.RLock()
+	defer fc.mu.RUnlock()
+
+	var sb strings.Builder
+	sb.WriteString("| Term | Definition | Mistakes |\n")
+	sb.WriteString("| --- | --- | --- |\n")
+	for _, key := range fc.keys() {
+		flashcard := fc.elements[key]
+		fmt.Fprintf(&sb, "| %s | %s | %d |\n",
+			markdownCellReplacer.Replace(flashcard.Term), markdownCellReplacer.Replace(flashcard.Definition), flashcard.Mistakes)
+	}
+
+	err := fc.writeDestination(filename, func(w io.Writer) error {
+		_, err := io.WriteString(w, sb.String())
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+	return len(fc.elements), nil
+}
+
+func (fc *Flashcards) WriteFile(filename string) (int, error) {
+	var write func(string) (int, error)
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".json":
+		write = fc.WriteJSON
+	case ".jsonl":
+		write = fc.WriteJSONL
+	case ".gob":
+		write = fc.WriteGob
+	default:
+		write = fc.WriteCSV
+	}
+	saved, err := write(filename)
+	if err != nil {
+		return 0, err
+	}
+	fc.MarkSaved()
+	return saved, nil
+}
+
+func (fc *Flashcards) loadFile(location string) ([]Flashcard, []SkippedRecord, error) {
+	switch sourceExt(location) {
+	case ".json":
+		return fc.loadJSON(location)
+	case ".jsonl":
+		return fc.loadJSONL(location)
+	case ".gob":
+		return fc.loadGob(location)
+	default:
+		return fc.loadCSV(location)
+	}
+}
+
+type ImportResult struct {
+	Added     int
+	Merged    int
+	Skipped   []SkippedRecord
+	Clamped   []string
+	OverLimit int
+}
+
+func clamp(flashcard *Flashcard) bool {
+	if flashcard.Mistakes >= 0 && flashcard.Correct >= 0 && flashcard.Streak >= 0 {
+		return false
+	}
+	flashcard.Mistakes = max(flashcard.Mistakes, 0)
+	flashcard.Correct = max(flashcard.Correct, 0)
+	flashcard.Streak = max(flashcard.Streak, 0)
+	return true
+}
+
+func clampCounts(flashcards []Flashcard) []string {
+	var clamped []string
+	for i := range flashcards {
+		if clamp(&flashcards[i]) {
+			clamped = append(clamped, flashcards[i].Term)
+		}
+	}
+	return clamped
+}
+
+type SkippedRecord struct {
+	Record int
+	Err    error
+}
+
+func (fc *Flashcards) importCard(flashcard Flashcard, merge bool, result *ImportResult) {
+	if !merge {
+		fc.createOrUpdate(flashcard)
+		result.Added++
+	} else if fc.merge(flashcard) {
+		result.Merged++
+	} else {
+		result.Added++
+	}
+}
+
+func (fc *Flashcards) Import(location string, merge bool) (ImportResult, error) {
+	flashcards, skipped, err := fc.loadFile(location)
+	if err != nil {
+		return ImportResult{}, err
+	}
+
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+	return fc.importCards(flashcards, skipped, merge), nil
+}
+
+func (fc *Flashcards) importCards(flashcards []Flashcard, skipped []SkippedRecord, merge bool) ImportResult {
+	result := ImportResult{Skipped: skipped}
+	flashcards, result.OverLimit = fc.capped(flashcards)
+	result.Clamped = clampCounts(flashcards)
+	if !merge {
+		fc.load(flashcards)
+		result.Added = len(flashcards)
+		return result
+	}
+	for _, flashcard := range flashcards {
+		fc.importCard(flashcard, merge, &result)
+	}
+	return result
+}
+
+func (fc *Flashcards) PreviewImport(location string) (added, termConflicts, definitionConflicts int, err error) {
+	flashcards, _, err := fc.loadFile(location)
+	if err != nil {
+		return 0, 0, 0, err
+	}
+
+	fc.mu.RLock()
+	defer fc.mu.RUnlock()
+	flashcards, _ = fc.capped(flashcards)
+	for _, flashcard := range flashcards {
+		if _, _, exists := fc.getByTerm(flashcard.Term); exists {
+			termConflicts++
+		} else if _, _, exists := fc.getByDefinition(flashcard.Definition); exists {
+			definitionConflicts++
+		} else {
+			added++
+		}
+	}
+	return added, termConflicts, definitionConflicts, nil
+}
+
+func (fc *Flashcards) ReadFile(location string) (int, error) {
+	result, err := fc.Import(location, false)
+	return result.Added, err
+}
+
+func (fc *Flashcards) MergeFile(location string) (added, merged int, err error) {
+	result, err := fc.Import(location, true)
+	return result.Added, result.Merged, err
+}
@@ -0,0 +1,912 @@
+package deck
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWriteAtomicallyKeepsMode(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "deck.csv")
+	if err := os.WriteFile(filename, []byte("old\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	err := writeAtomically(filename, func(w io.Writer) error {
+		_, err := io.WriteString(w, "new\n")
+		return err
+	})
+	if err != nil {
+		t.Fatalf("writeAtomically() = %v", err)
+	}
+	info, err := os.Stat(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mode := info.Mode().Perm(); mode != 0600 {
+		t.Errorf("mode = %v, want -rw-------", mode)
+	}
+}
+
+func TestWriteAtomicallyNewFileMode(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "deck.csv")
+
+	if err := writeAtomically(filename, func(w io.Writer) error { return nil }); err != nil {
+		t.Fatalf("writeAtomically() = %v", err)
+	}
+	info, err := os.Stat(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mode := info.Mode().Perm(); mode != 0644 {
+		t.Errorf("mode = %v, want -rw-r--r--", mode)
+	}
+}
+
+func TestWriteAtomicallyFailure(t *testing.T) {
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "deck.csv")
+	if err := os.WriteFile(filename, []byte("old\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	errWrite := errors.New("disk full")
+	err := writeAtomically(filename, func(w io.Writer) error {
+		io.WriteString(w, "half")
+		return errWrite
+	})
+	if !errors.Is(err, errWrite) {
+		t.Fatalf("writeAtomically() = %v, want %v", err, errWrite)
+	}
+	if data, _ := os.ReadFile(filename); string(data) != "old\n" {
+		t.Errorf("file = %q, want it untouched", data)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 1 {
+		t.Errorf("directory has %d entries, want the temporary file removed", len(entries))
+	}
+}
+
+func writeFixture(t *testing.T, name, content string) string {
+	t.Helper()
+	filename := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(filename, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return filename
+}
+
+func TestImportSkipsInvalidLastSeen(t *testing.T) {
+	filename := writeFixture(t, "deck.csv",
+		"cat,кот,0,,2024-01-02T03:04:05Z\n"+
+			"dog,собака,0,,yesterday\n"+
+			"cow,корова,0,,\n")
+	fc := NewFlashcards()
+
+	result, err := fc.Import(filename, false)
+	if err != nil {
+		t.Fatalf("Import() = %v", err)
+	}
+	if result.Added != 2 {
+		t.Errorf("added %d cards, want 2", result.Added)
+	}
+	if len(result.Skipped) != 1 || result.Skipped[0].Record != 2 || !errors.Is(result.Skipped[0].Err, ErrInvalidLastSeen) {
+		t.Errorf("skipped = %+v, want record 2 with ErrInvalidLastSeen", result.Skipped)
+	}
+	if flashcard, _ := fc.GetByTerm("cat"); flashcard.LastSeen.Year() != 2024 {
+		t.Errorf("cat last seen %v", flashcard.LastSeen)
+	}
+}
+
+func TestImportClampsAndValidatesCounts(t *testing.T) {
+	filename := writeFixture(t, "deck.csv",
+		"a,1,-2,,,3,1\n"+
+			"b,2,1,,,-4,2\n"+
+			"c,3,1,,,2,-1\n"+
+			"d,4,x,,,0,0\n"+
+			"e,5,0,,,many,0\n"+
+			"f,6,0,,,0,long\n"+
+			"g,7,2,,,5,3\n")
+	fc := NewFlashcards()
+
+	result, err := fc.Import(filename, false)
+	if err != nil {
+		t.Fatalf("Import() = %v", err)
+	}
+	if got := fmt.Sprint(result.Clamped); got != "[a b c]" {
+		t.Errorf("clamped = %s, want [a b c]", got)
+	}
+	wantSkipped := []SkippedRecord{{4, ErrInvalidMistakes}, {5, ErrInvalidCorrect}, {6, ErrInvalidStreak}}
+	if len(result.Skipped) != len(wantSkipped) {
+		t.Fatalf("skipped = %+v, want %+v", result.Skipped, wantSkipped)
+	}
+	for i, want := range wantSkipped {
+		if got := result.Skipped[i]; got.Record != want.Record || !errors.Is(got.Err, want.Err) {
+			t.Errorf("skipped[%d] = %+v, want %+v", i, got, want)
+		}
+	}
+
+	for _, flashcard := range fc.All() {
+		if flashcard.Mistakes < 0 || flashcard.Correct < 0 || flashcard.Streak < 0 {
+			t.Errorf("%s has negative counts: %+v", flashcard.Term, flashcard)
+		}
+	}
+	if flashcard, _ := fc.GetByTerm("g"); flashcard.Mistakes != 2 || flashcard.Correct != 5 || flashcard.Streak != 3 {
+		t.Errorf("g = %+v, want its counts kept", flashcard)
+	}
+}
+
+func TestReadJSONLClampsCounts(t *testing.T) {
+	filename := writeFixture(t, "deck.jsonl",
+		`{"term":"a","definition":"1","mistakes":-1,"correct":-2,"streak":-3}`+"\n")
+	fc := NewFlashcards()
+
+	if _, err := fc.ReadJSONL(filename); err != nil {
+		t.Fatalf("ReadJSONL() = %v", err)
+	}
+	if flashcard, _ := fc.GetByTerm("a"); flashcard.Mistakes != 0 || flashcard.Correct != 0 || flashcard.Streak != 0 {
+		t.Errorf("a = %+v, want zero counts", flashcard)
+	}
+}
+
+func TestImportJSONL(t *testing.T) {
+	filename := writeFixture(t, "deck.jsonl",
+		`{"term":"a","definition":"1","mistakes":2}`+"\n"+
+			`{"term":"","definition":"2"}`+"\n"+
+			"\n"+
+			`{"term":"b","definition":"3","mistakes":-1}`+"\n"+
+			`{"term":"c","definition":"4"}`+"\n"+
+			`{"term":"d","definition":"5"}`+"\n")
+	fc := NewFlashcards()
+	fc.SetMaxCards(2)
+
+	result, err := fc.Import(filename, false)
+	if err != nil {
+		t.Fatalf("Import() = %v", err)
+	}
+	if result.Added != 2 || result.OverLimit != 2 {
+		t.Errorf("added %d, over limit %d, want 2 and 2", result.Added, result.OverLimit)
+	}
+	if len(result.Skipped) != 1 || result.Skipped[0].Record != 2 || !errors.Is(result.Skipped[0].Err, ErrEmptyTerm) {
+		t.Errorf("skipped = %+v, want line 2 with ErrEmptyTerm", result.Skipped)
+	}
+	if fmt.Sprint(result.Clamped) != "[b]" {
+		t.Errorf("clamped = %v, want [b]", result.Clamped)
+	}
+	if got := fmt.Sprint(terms(fc.All())); got != "[a b]" {
+		t.Errorf("terms = %s, want [a b]", got)
+	}
+}
+
+func TestImportJSONLMerge(t *testing.T) {
+	filename := writeFixture(t, "deck.jsonl",
+		`{"term":"a","definition":"1","mistakes":2}`+"\n"+
+			`{"term":"b","definition":"2","mistakes":1}`+"\n")
+	fc := NewFlashcards()
+	fc.CreateOrUpdate(Flashcard{Term: "a", Definition: "1", Mistakes: 3})
+
+	result, err := fc.Import(filename, true)
+	if err != nil {
+		t.Fatalf("Import() = %v", err)
+	}
+	if result.Added != 1 || result.Merged != 1 {
+		t.Errorf("added %d, merged %d, want 1 and 1", result.Added, result.Merged)
+	}
+	if flashcard, _ := fc.GetByTerm("a"); flashcard.Mistakes != 5 {
+		t.Errorf("a has %d mistakes, want 5", flashcard.Mistakes)
+	}
+}
+
+func TestImportJSONLMalformedLine(t *testing.T) {
+	filename := writeFixture(t, "deck.jsonl", `{"term":"a","definition":"1"}`+"\n"+`{"term":"b","definition":"2"}`+"\n"+"{not json\n")
+	fc := newDeck(t, "cat", "кот")
+	fc.MarkSaved()
+
+	if _, err := fc.Import(filename, false); err == nil {
+		t.Error("Import() = nil, want an error for line 3")
+	}
+	if got := terms(fc.All()); !slices.Equal(got, []string{"cat"}) || fc.IsDirty() {
+		t.Errorf("deck = %v, dirty %v, want it unchanged after the error", got, fc.IsDirty())
+	}
+}
+
+func TestJSONLRoundTrip(t *testing.T) {
+	fc := NewFlashcards()
+	for i := 0; i < 10000; i++ {
+		fc.CreateOrUpdate(Flashcard{Term: fmt.Sprint("term", i), Definition: fmt.Sprint("definition\n", i), Mistakes: i % 7, Tags: []string{"tag"}})
+	}
+	filename := filepath.Join(t.TempDir(), "deck.jsonl")
+	if n, err := fc.WriteJSONL(filename); err != nil || n != 10000 {
+		t.Fatalf("WriteJSONL() = %d, %v", n, err)
+	}
+
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+	if len(lines) != 10000 {
+		t.Fatalf("wrote %d lines, want 10000", len(lines))
+	}
+	for i, line := range lines {
+		if !json.Valid([]byte(line)) {
+			t.Fatalf("line %d is not valid JSON: %q", i+1, line)
+		}
+	}
+
+	imported := NewFlashcards()
+	if n, err := imported.ReadJSONL(filename); err != nil || n != 10000 {
+		t.Fatalf("ReadJSONL() = %d, %v", n, err)
+	}
+	if !reflect.DeepEqual(imported.All(), fc.All()) {
+		t.Error("the imported deck differs from the written one")
+	}
+}
+
+func TestImportIntoEmptyDeck(t *testing.T) {
+	filename := writeFixture(t, "deck.csv", "a,1,1\nb,2,2\na,3,3\n")
+	fc := NewFlashcards()
+
+	result, err := fc.Import(filename, false)
+	if err != nil {
+		t.Fatalf("Import() = %v", err)
+	}
+	if result.Added != 3 {
+		t.Errorf("added %d, want every record counted", result.Added)
+	}
+	checkIndex(t, fc)
+	if got := fmt.Sprint(fc.All()); got != fmt.Sprint([]Flashcard{{Term: "a", Definition: "3", Mistakes: 3}, {Term: "b", Definition: "2", Mistakes: 2}}) {
+		t.Errorf("deck = %s, want the later a replacing the earlier one in place", got)
+	}
+	if !fc.IsDirty() {
+		t.Error("the deck isn't dirty after the import")
+	}
+}
+
+func BenchmarkImport(b *testing.B) {
+	var sb strings.Builder
+	for i := 0; i < 20000; i++ {
+		fmt.Fprintf(&sb, "term%d,definition%d,%d,tag,,%d,0\n", i, i, i%5, i%3)
+	}
+	filename := filepath.Join(b.TempDir(), "deck.csv")
+	if err := os.WriteFile(filename, []byte(sb.String()), 0644); err != nil {
+		b.Fatal(err)
+	}
+
+	b.Run("empty deck", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			if _, err := NewFlashcards().Import(filename, false); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("non-empty deck", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			fc := newDeck(b, "existing", "card")
+			if _, err := fc.Import(filename, false); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func TestImportQuizlet(t *testing.T) {
+	filename := writeFixture(t, "quizlet.txt", "cat\tкот\r\ndog\r\n\tпусто\r\ncow\tкорова\r\nfox\tлиса\r\n")
+	fc := NewFlashcards()
+	fc.SetMaxCards(2)
+
+	result, err := fc.ImportQuizlet(filename, "\t", "\n", false)
+	if err != nil {
+		t.Fatalf("ImportQuizlet() = %v", err)
+	}
+	if result.Added != 2 || result.OverLimit != 1 {
+		t.Errorf("added %d, over limit %d, want 2 and 1", result.Added, result.OverLimit)
+	}
+	wantSkipped := []SkippedRecord{{2, ErrTooFewFields}, {3, ErrEmptyTerm}}
+	if len(result.Skipped) != len(wantSkipped) {
+		t.Fatalf("skipped = %+v, want %+v", result.Skipped, wantSkipped)
+	}
+	for i, want := range wantSkipped {
+		if got := result.Skipped[i]; got.Record != want.Record || !errors.Is(got.Err, want.Err) {
+			t.Errorf("skipped[%d] = %+v, want %+v", i, got, want)
+		}
+	}
+	if definition, _ := fc.FindDefinitionByTerm("cow"); definition != "корова" {
+		t.Errorf("cow = %q, want the carriage return trimmed", definition)
+	}
+}
+
+func TestImportQuizletCustomDelimiters(t *testing.T) {
+	filename := writeFixture(t, "quizlet.txt", "cat - кот;dog - собака;")
+	fc := NewFlashcards()
+
+	loaded, err := fc.ReadQuizlet(filename, " - ", ";")
+	if err != nil {
+		t.Fatalf("ReadQuizlet() = %v", err)
+	}
+	if loaded != 2 {
+		t.Errorf("loaded %d cards, want 2", loaded)
+	}
+	if definition, _ := fc.FindDefinitionByTerm("dog"); definition != "собака" {
+		t.Errorf("dog = %q, want собака", definition)
+	}
+}
+
+func roundTripDeck(t *testing.T) *Flashcards {
+	t.Helper()
+	fc := NewFlashcards()
+	fc.CreateOrUpdate(Flashcard{Term: "cat", Definition: "кот", Mistakes: 2, Tags: []string{"pets"}, LastSeen: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC), Correct: 3, Streak: 1})
+	fc.CreateOrUpdate(Flashcard{Term: "dog", Definition: "собака"})
+	return fc
+}
+
+func TestJSONRoundTrip(t *testing.T) {
+	fc := roundTripDeck(t)
+	filename := filepath.Join(t.TempDir(), "deck.json")
+	if _, err := fc.WriteJSON(filename); err != nil {
+		t.Fatalf("WriteJSON() = %v", err)
+	}
+
+	imported := NewFlashcards()
+	if n, err := imported.ReadJSON(filename); err != nil || n != 2 {
+		t.Fatalf("ReadJSON() = %d, %v", n, err)
+	}
+	if !reflect.DeepEqual(imported.All(), fc.All()) {
+		t.Errorf("imported %+v, want %+v", imported.All(), fc.All())
+	}
+}
+
+func TestCSVDelimiters(t *testing.T) {
+	tests := []struct {
+		name      string
+		delimiter rune
+		content   string
+	}{
+		{"tab", '\t', "cat\tкот, кошка\t2\tpets,home\t\t0\t0\ndog\tсобака\t0\t\t\t0\t0\n"},
+		{"semicolon", ';', "cat;кот, кошка;2;pets,home;;0;0\ndog;собака;0;;;0;0\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fc := NewFlashcards()
+			fc.SetDelimiter(tt.delimiter)
+
+			if n, err := fc.ReadCSV(writeFixture(t, "deck.csv", tt.content)); err != nil || n != 2 {
+				t.Fatalf("ReadCSV() = %d, %v", n, err)
+			}
+			cat, _ := fc.GetByTerm("cat")
+			if cat.Definition != "кот, кошка" || cat.Mistakes != 2 || fmt.Sprint(cat.Tags) != "[pets home]" {
+				t.Errorf("cat = %+v", cat)
+			}
+
+			filename := filepath.Join(t.TempDir(), "out.csv")
+			if _, err := fc.WriteCSV(filename); err != nil {
+				t.Fatalf("WriteCSV() = %v", err)
+			}
+			if data, _ := os.ReadFile(filename); string(data) != tt.content {
+				t.Errorf("wrote %q, want %q", data, tt.content)
+			}
+		})
+	}
+}
+
+func TestWriteUnwritablePath(t *testing.T) {
+	fc := newDeck(t, "cat", "кот")
+	filename := filepath.Join(t.TempDir(), "missing", "deck.csv")
+
+	if n, err := fc.WriteCSV(filename); err == nil || n != 0 {
+		t.Errorf("WriteCSV() = %d, %v, want an error", n, err)
+	}
+	if _, err := fc.ReadCSV(filename); err == nil {
+		t.Error("ReadCSV() of a missing file succeeded")
+	}
+	if fc.Len() != 1 {
+		t.Errorf("deck has %d cards after the failed read", fc.Len())
+	}
+}
+
+func TestTagsRoundTrip(t *testing.T) {
+	for _, name := range []string{"deck.csv", "deck.json"} {
+		t.Run(name, func(t *testing.T) {
+			fc := NewFlashcards()
+			fc.CreateOrUpdate(Flashcard{Term: "cat", Definition: "кот", Tags: []string{"pets", "nouns"}})
+			fc.CreateOrUpdate(Flashcard{Term: "sun", Definition: "солнце"})
+			filename := filepath.Join(t.TempDir(), name)
+			if _, err := fc.WriteFile(filename); err != nil {
+				t.Fatalf("WriteFile() = %v", err)
+			}
+
+			imported := NewFlashcards()
+			if _, err := imported.ReadFile(filename); err != nil {
+				t.Fatalf("ReadFile() = %v", err)
+			}
+			if got := terms(imported.FilterByTag("nouns")); fmt.Sprint(got) != "[cat]" {
+				t.Errorf("FilterByTag(nouns) = %v, want [cat]", got)
+			}
+			if sun, _ := imported.GetByTerm("sun"); len(sun.Tags) != 0 {
+				t.Errorf("sun has tags %q", sun.Tags)
+			}
+		})
+	}
+}
+
+func TestImportFromURL(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/deck.csv", func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "cat,кот,1\ndog,собака\n")
+	})
+	mux.HandleFunc("/deck.json", func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, `[{"term":"cow","definition":"корова"}]`)
+	})
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+
+	fc := NewFlashcards()
+	if result, err := fc.Import(ts.URL+"/deck.csv", false); err != nil || result.Added != 2 {
+		t.Fatalf("Import(csv) = %+v, %v", result, err)
+	}
+	if cat, _ := fc.GetByTerm("cat"); cat.Mistakes != 1 {
+		t.Errorf("cat has %d mistakes, want 1", cat.Mistakes)
+	}
+	if result, err := fc.Import(ts.URL+"/deck.json?version=2", true); err != nil || result.Added != 1 {
+		t.Fatalf("Import(json) = %+v, %v", result, err)
+	}
+
+	_, err := fc.Import(ts.URL+"/missing.csv", false)
+	if err == nil || !strings.Contains(err.Error(), "404 Not Found") {
+		t.Errorf("Import(missing) = %v, want the status reported", err)
+	}
+	if fc.Len() != 3 {
+		t.Errorf("deck has %d cards after the failed import, want 3", fc.Len())
+	}
+}
+
+func TestWriteAnkiTSV(t *testing.T) {
+	fc := newDeck(t, "cat", "кот, кошка", "dog", "собака")
+	filename := filepath.Join(t.TempDir(), "deck.txt")
+
+	if n, err := fc.WriteAnkiTSV(filename); err != nil || n != 2 {
+		t.Fatalf("WriteAnkiTSV() = %d, %v", n, err)
+	}
+	if data, _ := os.ReadFile(filename); string(data) != "cat\tкот, кошка\ndog\tсобака\n" {
+		t.Errorf("wrote %q", data)
+	}
+
+	fc.CreateOrUpdate(Flashcard{Term: "bird", Definition: "птица\tпташка"})
+	if _, err := fc.WriteAnkiTSV(filename); err == nil {
+		t.Error("WriteAnkiTSV() accepted a definition with a tab")
+	}
+	if data, _ := os.ReadFile(filename); string(data) != "cat\tкот, кошка\ndog\tсобака\n" {
+		t.Errorf("the failed export changed the file to %q", data)
+	}
+}
+
+func TestMergeFile(t *testing.T) {
+	fc := NewFlashcards()
+	fc.CreateOrUpdate(Flashcard{Term: "cat", Definition: "кот", Mistakes: 2})
+	filename := writeFixture(t, "deck.csv", "cat,кошка,3\ndog,собака,1\n")
+
+	added, merged, err := fc.MergeFile(filename)
+	if err != nil || added != 1 || merged != 1 {
+		t.Fatalf("MergeFile() = %d, %d, %v, want 1 added and 1 merged", added, merged, err)
+	}
+	if cat, _ := fc.GetByTerm("cat"); cat.Definition != "кот" || cat.Mistakes != 5 {
+		t.Errorf("cat = %+v, want the existing definition and 5 mistakes", cat)
+	}
+}
+
+func TestStdStream(t *testing.T) {
+	var stdout strings.Builder
+	fc := NewFlashcards()
+	fc.SetStdio(strings.NewReader("cat,кот,1\ndog,собака\n"), &stdout)
+
+	if n, err := fc.ReadCSV(StdStream); err != nil || n != 2 {
+		t.Fatalf("ReadCSV(-) = %d, %v", n, err)
+	}
+	if n, err := fc.WriteCSV(StdStream); err != nil || n != 2 {
+		t.Fatalf("WriteCSV(-) = %d, %v", n, err)
+	}
+	if want := "cat,кот,1,,,0,0\ndog,собака,0,,,0,0\n"; stdout.String() != want {
+		t.Errorf("wrote %q, want %q", stdout.String(), want)
+	}
+}
+
+func TestWriteHardestCSV(t *testing.T) {
+	fc := newDeck(t, "a", "1", "b", "2", "c", "3", "d", "4")
+	fc.SetMistakes("a", 1)
+	fc.SetMistakes("b", 4)
+	fc.SetMistakes("d", 4)
+	filename := filepath.Join(t.TempDir(), "hardest.csv")
+
+	if n, err := fc.WriteHardestCSV(filename); err != nil || n != 2 {
+		t.Fatalf("WriteHardestCSV() = %d, %v", n, err)
+	}
+	if data, _ := os.ReadFile(filename); string(data) != "b,2,4,,,0,0\nd,4,4,,,0,0\n" {
+		t.Errorf("wrote %q", data)
+	}
+}
+
+func TestWriteHardestCSVWithoutMistakes(t *testing.T) {
+	fc := newDeck(t, "a", "1")
+	filename := filepath.Join(t.TempDir(), "hardest.csv")
+
+	if n, err := fc.WriteHardestCSV(filename); err != nil || n != 0 {
+		t.Fatalf("WriteHardestCSV() = %d, %v", n, err)
+	}
+	if _, err := os.Stat(filename); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Stat() = %v, want no file written", err)
+	}
+}
+
+func TestReadCSVSkipsBlankCards(t *testing.T) {
+	filename := writeFixture(t, "deck.csv", "cat,кот\n ,пусто\ndog,\t\n")
+	fc := NewFlashcards()
+
+	result, err := fc.Import(filename, false)
+	if err != nil {
+		t.Fatalf("Import() = %v", err)
+	}
+	wantSkipped := []SkippedRecord{{2, ErrEmptyTerm}, {3, ErrEmptyDefinition}}
+	if result.Added != 1 || fmt.Sprint(result.Skipped) != fmt.Sprint(wantSkipped) {
+		t.Errorf("Import() = %+v, want 1 added and %v skipped", result, wantSkipped)
+	}
+}
+
+func TestCSVMultilineDefinition(t *testing.T) {
+	fc := newDeck(t, "run", "бежать\nI run every day.", "cat", "кот")
+	filename := filepath.Join(t.TempDir(), "deck.csv")
+	if _, err := fc.WriteCSV(filename); err != nil {
+		t.Fatalf("WriteCSV() = %v", err)
+	}
+	if data, _ := os.ReadFile(filename); string(data) != "run,\"бежать\nI run every day.\",0,,,0,0\ncat,кот,0,,,0,0\n" {
+		t.Errorf("wrote %q", data)
+	}
+
+	imported := NewFlashcards()
+	if n, err := imported.ReadCSV(filename); err != nil || n != 2 {
+		t.Fatalf("ReadCSV() = %d, %v", n, err)
+	}
+	if definition, _ := imported.FindDefinitionByTerm("run"); definition != "бежать\nI run every day." {
+		t.Errorf("run = %q", definition)
+	}
+}
+
+func TestWriteMarkdown(t *testing.T) {
+	fc := NewFlashcards()
+	fc.CreateOrUpdate(Flashcard{Term: "a | b", Definition: "или", Mistakes: 2})
+	fc.CreateOrUpdate(Flashcard{Term: "run", Definition: "бежать\nI run."})
+	filename := filepath.Join(t.TempDir(), "deck.md")
+
+	if n, err := fc.WriteMarkdown(filename); err != nil || n != 2 {
+		t.Fatalf("WriteMarkdown() = %d, %v", n, err)
+	}
+	got, _ := os.ReadFile(filename)
+	want, err := os.ReadFile(filepath.Join("testdata", "deck.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != string(want) {
+		t.Errorf("wrote:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestImportMaxCards(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+	}{
+		{"deck.csv", "a,1\nb,2\nc,3\nd,4\ne,5\n"},
+		{"deck.jsonl", `{"term":"a","definition":"1"}
+{"term":"b","definition":"2"}
+{"term":"c","definition":"3"}
+{"term":"d","definition":"4"}
+{"term":"e","definition":"5"}
+`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fc := NewFlashcards()
+			fc.SetMaxCards(3)
+
+			result, err := fc.Import(writeFixture(t, tt.name, tt.content), false)
+			if err != nil {
+				t.Fatalf("Import() = %v", err)
+			}
+			if result.Added != 3 || result.OverLimit != 2 || fc.Len() != 3 {
+				t.Errorf("Import() = %+v with %d cards, want 3 added and 2 over the limit", result, fc.Len())
+			}
+			if got := terms(fc.All()); fmt.Sprint(got) != "[a b c]" {
+				t.Errorf("terms = %v, want the first three", got)
+			}
+		})
+	}
+}
+
+func TestWriteReversedCSV(t *testing.T) {
+	fc := newDeck(t, "cat", "кот", "dog", "собака")
+	fc.SetMistakes("cat", 3)
+	fc.MarkSaved()
+	filename := filepath.Join(t.TempDir(), "reversed.csv")
+
+	if n, err := fc.WriteReversedCSV(filename); err != nil || n != 2 {
+		t.Fatalf("WriteReversedCSV() = %d, %v", n, err)
+	}
+	if definition, _ := fc.FindDefinitionByTerm("cat"); definition != "кот" || fc.IsDirty() {
+		t.Errorf("the deck changed: cat = %q, dirty = %v", definition, fc.IsDirty())
+	}
+
+	reversed := NewFlashcards()
+	if n, err := reversed.ReadCSV(filename); err != nil || n != 2 {
+		t.Fatalf("ReadCSV() = %d, %v", n, err)
+	}
+	if flashcard, _ := reversed.GetByTerm("кот"); flashcard.Definition != "cat" || flashcard.Mistakes != 3 {
+		t.Errorf("кот = %+v, want cat with 3 mistakes", flashcard)
+	}
+	if term, _ := reversed.FindTermByDefinition("dog"); term != "собака" {
+		t.Errorf("dog is the definition of %q", term)
+	}
+}
+
+func TestDirtyFlag(t *testing.T) {
+	fc := NewFlashcards()
+	if fc.IsDirty() {
+		t.Error("a new deck is dirty")
+	}
+	fc.CreateOrUpdate(Flashcard{Term: "cat", Definition: "кот"})
+	if !fc.IsDirty() {
+		t.Error("not dirty after an add")
+	}
+
+	filename := filepath.Join(t.TempDir(), "deck.csv")
+	if _, err := fc.WriteFile(filename); err != nil {
+		t.Fatalf("WriteFile() = %v", err)
+	}
+	if fc.IsDirty() {
+		t.Error("dirty after a save")
+	}
+	fc.RemoveByTerm("dog")
+	if fc.IsDirty() {
+		t.Error("dirty after removing a missing card")
+	}
+	fc.RemoveByTerm("cat")
+	if !fc.IsDirty() {
+		t.Error("not dirty after a removal")
+	}
+	fc.MarkSaved()
+	if fc.IsDirty() {
+		t.Error("dirty after MarkSaved")
+	}
+
+	fc.CreateOrUpdate(Flashcard{Term: "dog", Definition: "собака"})
+	if _, err := fc.WriteFile(filepath.Join(t.TempDir(), "missing", "deck.csv")); err == nil || !fc.IsDirty() {
+		t.Errorf("WriteFile() = %v, dirty = %v, want a failed save to keep the deck dirty", err, fc.IsDirty())
+	}
+}
+
+func TestPreviewImport(t *testing.T) {
+	fc := newDeck(t, "cat", "кот", "dog", "собака")
+	fc.SetMistakes("cat", 2)
+	fc.MarkSaved()
+	before := fmt.Sprint(fc.All())
+	filename := writeFixture(t, "deck.csv", "cat,кошка\ncow,корова\nhound,собака\nbird,птица\ndog,пёс\n")
+
+	added, termConflicts, definitionConflicts, err := fc.PreviewImport(filename)
+	if err != nil {
+		t.Fatalf("PreviewImport() = %v", err)
+	}
+	if added != 2 || termConflicts != 2 || definitionConflicts != 1 {
+		t.Errorf("PreviewImport() = %d, %d, %d, want 2, 2, 1", added, termConflicts, definitionConflicts)
+	}
+	if after := fmt.Sprint(fc.All()); after != before || fc.IsDirty() {
+		t.Errorf("deck = %s, dirty = %v, want it unchanged", after, fc.IsDirty())
+	}
+	if _, _, _, err := fc.PreviewImport(filepath.Join(t.TempDir(), "missing.csv")); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("PreviewImport(missing) = %v, want %v", err, os.ErrNotExist)
+	}
+}
+
+func TestGobRoundTrip(t *testing.T) {
+	fc := roundTripDeck(t)
+	filename := filepath.Join(t.TempDir(), "deck.gob")
+	if n, err := fc.WriteFile(filename); err != nil || n != 2 {
+		t.Fatalf("WriteFile() = %d, %v", n, err)
+	}
+
+	imported := NewFlashcards()
+	if n, err := imported.ReadFile(filename); err != nil || n != 2 {
+		t.Fatalf("ReadFile() = %d, %v", n, err)
+	}
+	if !reflect.DeepEqual(imported.All(), fc.All()) {
+		t.Errorf("imported %+v, want %+v", imported.All(), fc.All())
+	}
+}
+
+func BenchmarkLoad(b *testing.B) {
+	fc := NewFlashcards()
+	for i := 0; i < 50000; i++ {
+		fc.CreateOrUpdate(Flashcard{Term: fmt.Sprint("term", i), Definition: fmt.Sprint("definition", i), Mistakes: i % 7, Tags: []string{"generated"}})
+	}
+	dir := b.TempDir()
+	for _, name := range []string{"deck.gob", "deck.csv"} {
+		filename := filepath.Join(dir, name)
+		if _, err := fc.WriteFile(filename); err != nil {
+			b.Fatal(err)
+		}
+		b.Run(name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				if _, err := NewFlashcards().ReadFile(filename); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func TestCSVHeader(t *testing.T) {
+	fc := NewFlashcards()
+	fc.SetHeader(true)
+	fc.CreateOrUpdate(Flashcard{Term: "cat", Definition: "кот", Mistakes: 2, Tags: []string{"pets"}, Correct: 1, Streak: 1})
+	filename := filepath.Join(t.TempDir(), "deck.csv")
+	if _, err := fc.WriteCSV(filename); err != nil {
+		t.Fatalf("WriteCSV() = %v", err)
+	}
+	if data, _ := os.ReadFile(filename); string(data) != "term,definition,mistakes,tags,lastSeen,correct,streak\ncat,кот,2,pets,,1,1\n" {
+		t.Errorf("wrote %q", data)
+	}
+
+	imported := NewFlashcards()
+	if n, err := imported.ReadCSV(filename); err != nil || n != 1 {
+		t.Fatalf("ReadCSV() = %d, %v", n, err)
+	}
+	if !reflect.DeepEqual(imported.All(), fc.All()) {
+		t.Errorf("imported %+v, want %+v", imported.All(), fc.All())
+	}
+}
+
+func TestCSVReorderedColumns(t *testing.T) {
+	filename := writeFixture(t, "deck.csv", "Mistakes, Definition ,TERM,notes\n3,кот,cat,furry\n,собака,dog,\n")
+	fc := NewFlashcards()
+
+	if n, err := fc.ReadCSV(filename); err != nil || n != 2 {
+		t.Fatalf("ReadCSV() = %d, %v", n, err)
+	}
+	if cat, _ := fc.GetByTerm("cat"); cat.Definition != "кот" || cat.Mistakes != 3 {
+		t.Errorf("cat = %+v", cat)
+	}
+	if dog, _ := fc.GetByTerm("dog"); dog.Definition != "собака" || dog.Mistakes != 0 {
+		t.Errorf("dog = %+v", dog)
+	}
+}
+
+func TestCSVWithoutHeader(t *testing.T) {
+	filename := writeFixture(t, "deck.csv", "term,термин\ncat,кот\n")
+	fc := NewFlashcards()
+
+	if n, err := fc.ReadCSV(filename); err != nil || n != 2 {
+		t.Fatalf("ReadCSV() = %d, %v, want a row without a definition column read as a card", n, err)
+	}
+}
+
+func TestCSVCommentsAndBlankLines(t *testing.T) {
+	content := "# animals\ncat,кот\n\n\n# pets too\ndog,собака,1\n\n"
+	filename := writeFixture(t, "deck.csv", content)
+
+	fc := NewFlashcards()
+	fc.SetComments(true)
+	result, err := fc.Import(filename, false)
+	if err != nil {
+		t.Fatalf("Import() = %v", err)
+	}
+	if got := terms(fc.All()); fmt.Sprint(got) != "[cat dog]" || len(result.Skipped) != 0 {
+		t.Errorf("loaded %v, skipped %+v, want only cat and dog", got, result.Skipped)
+	}
+
+	plain := NewFlashcards()
+	result, err = plain.Import(filename, false)
+	if err != nil {
+		t.Fatalf("Import() = %v", err)
+	}
+	if got := terms(plain.All()); fmt.Sprint(got) != "[cat dog]" {
+		t.Errorf("without comments loaded %v", got)
+	}
+	if len(result.Skipped) != 2 {
+		t.Errorf("without comments skipped %+v, want the comment lines reported", result.Skipped)
+	}
+}
+
+func TestWriteStatsJSON(t *testing.T) {
+	fc := NewFlashcards()
+	fc.CreateOrUpdate(Flashcard{Term: "cat", Definition: "кот", Mistakes: 3, Correct: 1})
+	fc.CreateOrUpdate(Flashcard{Term: "dog", Definition: "собака", Correct: 3, Streak: 3})
+	filename := filepath.Join(t.TempDir(), "stats.json")
+	if err := fc.WriteStatsJSON(filename); err != nil {
+		t.Fatalf("WriteStatsJSON() = %v", err)
+	}
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var schema map[string]any
+	if err := json.Unmarshal(data, &schema); err != nil {
+		t.Fatalf("the report isn't JSON: %v", err)
+	}
+	var keys []string
+	for key := range schema {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	if got := fmt.Sprint(keys); got != "[accuracy averageMistakes cards cardsWithMistakes hardest perCard totalCorrect totalMistakes]" {
+		t.Errorf("report keys = %s", got)
+	}
+	perCard, _ := schema["perCard"].([]any)
+	if len(perCard) != 2 {
+		t.Fatalf("perCard = %v, want 2 entries", schema["perCard"])
+	}
+	cardKeys := []string{}
+	for key := range perCard[0].(map[string]any) {
+		cardKeys = append(cardKeys, key)
+	}
+	slices.Sort(cardKeys)
+	if got := fmt.Sprint(cardKeys); got != "[accuracy correct mistakes streak term]" {
+		t.Errorf("card keys = %s", got)
+	}
+
+	var report StatsReport
+	if err := json.Unmarshal(data, &report); err != nil {
+		t.Fatal(err)
+	}
+	want := StatsReport{
+		Cards:             2,
+		CardsWithMistakes: 1,
+		TotalMistakes:     3,
+		TotalCorrect:      4,
+		AverageMistakes:   1.5,
+		Accuracy:          4.0 / 7,
+		Hardest:           []string{"cat"},
+		PerCard: []CardReport{
+			{Term: "cat", Mistakes: 3, Correct: 1, Accuracy: 0.25},
+			{Term: "dog", Correct: 3, Accuracy: 1, Streak: 3},
+		},
+	}
+	if !reflect.DeepEqual(report, want) {
+		t.Errorf("report = %+v, want %+v", report, want)
+	}
+}
+
+func TestSplitCSV(t *testing.T) {
+	fc := NewFlashcards()
+	for i := 0; i < 10; i++ {
+		fc.CreateOrUpdate(Flashcard{Term: fmt.Sprint("term", i), Definition: fmt.Sprint("definition", i)})
+	}
+	dir := t.TempDir()
+
+	filenames, err := fc.SplitCSV(filepath.Join(dir, "deck.csv"), 3)
+	if err != nil {
+		t.Fatalf("SplitCSV() = %v", err)
+	}
+	want := []string{filepath.Join(dir, "deck_1.csv"), filepath.Join(dir, "deck_2.csv"), filepath.Join(dir, "deck_3.csv")}
+	if !slices.Equal(filenames, want) {
+		t.Fatalf("SplitCSV() wrote %v, want %v", filenames, want)
+	}
+
+	var all []string
+	for i, size := range []int{4, 3, 3} {
+		part := NewFlashcards()
+		if n, err := part.ReadFile(filenames[i]); err != nil || n != size {
+			t.Errorf("ReadFile(%s) = %d, %v, want %d cards", filenames[i], n, err, size)
+		}
+		all = append(all, terms(part.All())...)
+	}
+	if !slices.Equal(all, terms(fc.All())) {
+		t.Errorf("parts hold %v, want %v", all, terms(fc.All()))
+	}
+}
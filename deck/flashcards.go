@@ -0,0 +1,1060 @@
+package deck
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+type Flashcard struct {
+	Term       string    `json:"term"`
+	Definition string    `json:"definition"`
+	Mistakes   int       `json:"mistakes"`
+	Tags       []string  `json:"tags,omitempty"`
+	LastSeen   time.Time `json:"lastSeen"`
+	Correct    int       `json:"correct"`
+	Streak     int       `json:"streak"`
+}
+
+func (f Flashcard) Accuracy() float64 {
+	if f.Correct+f.Mistakes == 0 {
+		return 0
+	}
+	return float64(f.Correct) / float64(f.Correct+f.Mistakes)
+}
+
+func (f Flashcard) String() string {
+	mistakes := "mistakes"
+	if f.Mistakes == 1 {
+		mistakes = "mistake"
+	}
+	return fmt.Sprintf("%q -> %q (%d %s)", f.Term, f.Definition, f.Mistakes, mistakes)
+}
+
+func (f Flashcard) HasTag(tag string) bool {
+	return slices.Contains(f.Tags, tag)
+}
+
+const maxWeightAge = 7 * 24 * time.Hour
+
+func (f Flashcard) weight(now time.Time) float64 {
+	age := maxWeightAge
+	if !f.LastSeen.IsZero() {
+		age = min(now.Sub(f.LastSeen), maxWeightAge)
+	}
+	return float64(f.Mistakes+1) * (1 + age.Hours())
+}
+
+var (
+	ErrEmptyTerm        = errors.New("the term is empty")
+	ErrEmptyDefinition  = errors.New("the definition is empty")
+	ErrNoSuchCard       = errors.New("there is no such card")
+	ErrTermExists       = errors.New("another card already has this term")
+	ErrDefinitionExists = errors.New("another card already has this definition")
+	ErrTooFewFields     = errors.New("the row has fewer than 2 fields")
+	ErrInvalidMistakes  = errors.New("the number of mistakes is not a number")
+	ErrInvalidLastSeen  = errors.New("the last seen time is not an RFC 3339 time")
+	ErrInvalidCorrect   = errors.New("the number of correct answers is not a number")
+	ErrInvalidStreak    = errors.New("the streak is not a number")
+)
+
+func ValidateTerm(term string) error {
+	if strings.TrimSpace(term) == "" {
+		return ErrEmptyTerm
+	}
+	return nil
+}
+
+func ValidateDefinition(definition string) error {
+	if strings.TrimSpace(definition) == "" {
+		return ErrEmptyDefinition
+	}
+	return nil
+}
+
+func Validate(term, definition string) error {
+	if err := ValidateTerm(term); err != nil {
+		return err
+	}
+	return ValidateDefinition(definition)
+}
+
+func ParseTags(s string) []string {
+	var tags []string
+	for _, tag := range strings.Split(s, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag != "" && !slices.Contains(tags, tag) {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
+type Flashcards struct {
+	mu        sync.RWMutex
+	elements  map[int]Flashcard
+	byTerm    map[string]int
+	nextID    int
+	delimiter rune
+	header    bool
+	comments  bool
+	maxCards  int
+	dirty     bool
+	rng       *rand.Rand
+	now       func() time.Time
+	stdin     io.Reader
+	stdout    io.Writer
+}
+
+func NewFlashcards() *Flashcards {
+	return &Flashcards{
+		elements: make(map[int]Flashcard),
+		byTerm:   make(map[string]int),
+		rng:      rand.New(rand.NewSource(time.Now().UnixNano())),
+		now:      time.Now,
+		stdin:    os.Stdin,
+		stdout:   os.Stdout,
+	}
+}
+
+func (fc *Flashcards) SetStdio(stdin io.Reader, stdout io.Writer) {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+	fc.stdin = stdin
+	fc.stdout = stdout
+}
+
+func (fc *Flashcards) SetSeed(seed int64) {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+	fc.rng = rand.New(rand.NewSource(seed))
+}
+
+func (fc *Flashcards) SetClock(now func() time.Time) {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+	fc.now = now
+}
+
+func (fc *Flashcards) SetDelimiter(delimiter rune) {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+	fc.delimiter = delimiter
+}
+
+func (fc *Flashcards) SetHeader(header bool) {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+	fc.header = header
+}
+
+func (fc *Flashcards) SetComments(comments bool) {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+	fc.comments = comments
+}
+
+func (fc *Flashcards) SetMaxCards(n int) {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+	fc.maxCards = n
+}
+
+func (fc *Flashcards) capped(flashcards []Flashcard) ([]Flashcard, int) {
+	if fc.maxCards <= 0 || len(flashcards) <= fc.maxCards {
+		return flashcards, 0
+	}
+	return flashcards[:fc.maxCards], len(flashcards) - fc.maxCards
+}
+
+func (fc *Flashcards) FindDefinitionByTerm(term string) (string, bool) {
+	fc.mu.RLock()
+	defer fc.mu.RUnlock()
+
+	flashcard, _, exists := fc.getByTerm(term)
+	return flashcard.Definition, exists
+}
+
+func (fc *Flashcards) FindTermByDefinition(definition string) (string, bool) {
+	fc.mu.RLock()
+	defer fc.mu.RUnlock()
+
+	flashcard, _, exists := fc.getByDefinition(definition)
+	return flashcard.Term, exists
+}
+
+func (fc *Flashcards) FindAllTermsByDefinition(definition string) []string {
+	fc.mu.RLock()
+	defer fc.mu.RUnlock()
+
+	var terms []string
+	for _, key := range fc.keys() {
+		if flashcard := fc.elements[key]; flashcard.Definition == definition {
+			terms = append(terms, flashcard.Term)
+		}
+	}
+	return terms
+}
+
+func (fc *Flashcards) Add(flashcard Flashcard) error {
+	if err := Validate(flashcard.Term, flashcard.Definition); err != nil {
+		return err
+	}
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+
+	if _, _, exists := fc.getByTerm(flashcard.Term); exists {
+		return ErrTermExists
+	}
+	if _, _, exists := fc.getByDefinition(flashcard.Definition); exists {
+		return ErrDefinitionExists
+	}
+	fc.add(flashcard)
+	fc.dirty = true
+	return nil
+}
+
+func (fc *Flashcards) CreateOrUpdate(flashcard Flashcard) {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+	fc.createOrUpdate(flashcard)
+}
+
+func (fc *Flashcards) createOrUpdate(flashcard Flashcard) {
+	fc.dirty = true
+	if _, key, exists := fc.getByTerm(flashcard.Term); exists {
+		fc.elements[key] = flashcard
+		return
+	}
+	fc.add(flashcard)
+}
+
+func (fc *Flashcards) load(flashcards []Flashcard) {
+	clampCounts(flashcards)
+	if len(fc.elements) > 0 {
+		for _, flashcard := range flashcards {
+			fc.createOrUpdate(flashcard)
+		}
+		return
+	}
+
+	fc.elements = make(map[int]Flashcard, len(flashcards))
+	fc.byTerm = make(map[string]int, len(flashcards))
+	for _, flashcard := range flashcards {
+		if key, exists := fc.byTerm[flashcard.Term]; exists {
+			fc.elements[key] = flashcard
+			continue
+		}
+		fc.add(flashcard)
+	}
+	if len(flashcards) > 0 {
+		fc.dirty = true
+	}
+}
+
+func (fc *Flashcards) add(flashcard Flashcard) {
+	fc.elements[fc.nextID] = flashcard
+	fc.byTerm[flashcard.Term] = fc.nextID
+	fc.nextID++
+}
+
+func (fc *Flashcards) reindex() {
+	fc.byTerm = make(map[string]int, len(fc.elements))
+	for _, key := range fc.keys() {
+		if _, exists := fc.byTerm[fc.elements[key].Term]; !exists {
+			fc.byTerm[fc.elements[key].Term] = key
+		}
+	}
+}
+
+func (fc *Flashcards) Merge(flashcard Flashcard) bool {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+	return fc.merge(flashcard)
+}
+
+func (fc *Flashcards) merge(flashcard Flashcard) bool {
+	fc.dirty = true
+	if existingFlashcard, key, exists := fc.getByTerm(flashcard.Term); exists {
+		fc.elements[key] = combine(existingFlashcard, flashcard)
+		return true
+	}
+	fc.add(flashcard)
+	return false
+}
+
+func combine(survivor, duplicate Flashcard) Flashcard {
+	survivor.Mistakes += duplicate.Mistakes
+	survivor.Correct += duplicate.Correct
+	for _, tag := range duplicate.Tags {
+		if !survivor.HasTag(tag) {
+			survivor.Tags = append(survivor.Tags, tag)
+		}
+	}
+	if duplicate.LastSeen.After(survivor.LastSeen) {
+		survivor.LastSeen = duplicate.LastSeen
+	}
+	return survivor
+}
+
+func (fc *Flashcards) Deduplicate() int {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+
+	byTerm := make(map[string]int)
+	byDefinition := make(map[string]int)
+	removed := 0
+	for _, key := range fc.keys() {
+		flashcard := fc.elements[key]
+		survivor, exists := byTerm[flashcard.Term]
+		if !exists {
+			survivor, exists = byDefinition[flashcard.Definition]
+		}
+		if exists {
+			fc.elements[survivor] = combine(fc.elements[survivor], flashcard)
+			delete(fc.elements, key)
+			removed++
+			continue
+		}
+		byTerm[flashcard.Term] = key
+		byDefinition[flashcard.Definition] = key
+	}
+	if removed > 0 {
+		fc.reindex()
+		fc.dirty = true
+	}
+	return removed
+}
+
+func (fc *Flashcards) RemoveByTerm(term string) bool {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+
+	_, key, exists := fc.getByTerm(term)
+	if !exists {
+		return false
+	}
+	delete(fc.elements, key)
+	delete(fc.byTerm, term)
+	fc.dirty = true
+	return true
+}
+
+func (fc *Flashcards) GetByTerm(term string) (Flashcard, bool) {
+	fc.mu.RLock()
+	defer fc.mu.RUnlock()
+
+	flashcard, _, exists := fc.getByTerm(term)
+	return flashcard, exists
+}
+
+func (fc *Flashcards) getByTerm(term string) (Flashcard, int, bool) {
+	key, exists := fc.byTerm[term]
+	if !exists {
+		return Flashcard{}, 0, false
+	}
+	return fc.elements[key], key, true
+}
+
+func (fc *Flashcards) rename(key int, oldTerm, newTerm string) {
+	if oldTerm == newTerm {
+		return
+	}
+	delete(fc.byTerm, oldTerm)
+	fc.byTerm[newTerm] = key
+}
+
+func (fc *Flashcards) getByDefinition(definition string) (Flashcard, int, bool) {
+	for key, flashcard := range fc.elements {
+		if flashcard.Definition == definition {
+			return flashcard, key, true
+		}
+	}
+	return Flashcard{}, 0, false
+}
+
+func (fc *Flashcards) Edit(term, newTerm, newDefinition string, newTags []string) error {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+
+	flashcard, key, exists := fc.getByTerm(term)
+	if !exists {
+		return ErrNoSuchCard
+	}
+	if _, otherKey, exists := fc.getByTerm(newTerm); exists && otherKey != key {
+		return ErrTermExists
+	}
+	if _, otherKey, exists := fc.getByDefinition(newDefinition); exists && otherKey != key {
+		return ErrDefinitionExists
+	}
+	flashcard.Term = newTerm
+	flashcard.Definition = newDefinition
+	flashcard.Tags = newTags
+	fc.elements[key] = flashcard
+	fc.rename(key, term, newTerm)
+	fc.dirty = true
+	return nil
+}
+
+func (fc *Flashcards) Copy(term, newTerm string) bool {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+
+	source, _, exists := fc.getByTerm(term)
+	if !exists {
+		return false
+	}
+	if _, _, exists := fc.getByTerm(newTerm); exists {
+		return false
+	}
+	fc.createOrUpdate(Flashcard{Term: newTerm, Definition: source.Definition, Tags: slices.Clone(source.Tags)})
+	return true
+}
+
+func (fc *Flashcards) Swap(term string) error {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+
+	flashcard, key, exists := fc.getByTerm(term)
+	if !exists {
+		return ErrNoSuchCard
+	}
+	if _, otherKey, exists := fc.getByTerm(flashcard.Definition); exists && otherKey != key {
+		return ErrTermExists
+	}
+	if _, otherKey, exists := fc.getByDefinition(flashcard.Term); exists && otherKey != key {
+		return ErrDefinitionExists
+	}
+	flashcard.Term, flashcard.Definition = flashcard.Definition, flashcard.Term
+	fc.elements[key] = flashcard
+	fc.rename(key, term, flashcard.Term)
+	fc.dirty = true
+	return nil
+}
+
+type Snapshot struct {
+	elements map[int]Flashcard
+	nextID   int
+}
+
+func (fc *Flashcards) Snapshot() Snapshot {
+	fc.mu.RLock()
+	defer fc.mu.RUnlock()
+
+	elements := make(map[int]Flashcard, len(fc.elements))
+	for key, flashcard := range fc.elements {
+		flashcard.Tags = slices.Clone(flashcard.Tags)
+		elements[key] = flashcard
+	}
+	return Snapshot{elements: elements, nextID: fc.nextID}
+}
+
+func (fc *Flashcards) Restore(snapshot Snapshot) {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+
+	fc.elements = snapshot.elements
+	fc.nextID = snapshot.nextID
+	fc.reindex()
+	fc.dirty = true
+}
+
+func (fc *Flashcards) keys() []int {
+	keys := make([]int, 0, len(fc.elements))
+	for key := range fc.elements {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+func (fc *Flashcards) All() []Flashcard {
+	fc.mu.RLock()
+	defer fc.mu.RUnlock()
+	return fc.all()
+}
+
+func (fc *Flashcards) all() []Flashcard {
+	flashcards := make([]Flashcard, 0, len(fc.elements))
+	for _, key := range fc.keys() {
+		flashcards = append(flashcards, fc.elements[key])
+	}
+	return flashcards
+}
+
+func (fc *Flashcards) Len() int {
+	fc.mu.RLock()
+	defer fc.mu.RUnlock()
+
+	return len(fc.elements)
+}
+
+func (fc *Flashcards) GetRandomFc() (Flashcard, bool) {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+
+	keys := fc.keys()
+	if len(keys) == 0 {
+		return Flashcard{}, false
+	}
+	return fc.elements[keys[fc.rng.Intn(len(keys))]], true
+}
+
+func (fc *Flashcards) GetWeightedRandomFc() (Flashcard, bool) {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+
+	keys := fc.keys()
+	if len(keys) == 0 {
+		return Flashcard{}, false
+	}
+
+	now := fc.now()
+	weights := make([]float64, len(keys))
+	total := 0.0
+	for i, key := range keys {
+		weights[i] = fc.elements[key].weight(now)
+		total += weights[i]
+	}
+
+	r := fc.rng.Float64() * total
+	for i, key := range keys {
+		r -= weights[i]
+		if r < 0 {
+			return fc.elements[key], true
+		}
+	}
+	return fc.elements[keys[len(keys)-1]], true
+}
+
+func (fc *Flashcards) Shuffle() []Flashcard {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+
+	flashcards := fc.all()
+	fc.rng.Shuffle(len(flashcards), func(i, j int) {
+		flashcards[i], flashcards[j] = flashcards[j], flashcards[i]
+	})
+	return flashcards
+}
+
+func (fc *Flashcards) ShuffleOrder() {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+
+	flashcards := fc.all()
+	fc.rng.Shuffle(len(flashcards), func(i, j int) {
+		flashcards[i], flashcards[j] = flashcards[j], flashcards[i]
+	})
+	fc.elements = make(map[int]Flashcard, len(flashcards))
+	fc.byTerm = make(map[string]int, len(flashcards))
+	fc.nextID = 0
+	for _, flashcard := range flashcards {
+		fc.add(flashcard)
+	}
+	fc.dirty = true
+}
+
+func (fc *Flashcards) SortedByTerm() []Flashcard {
+	fc.mu.RLock()
+	defer fc.mu.RUnlock()
+
+	flashcards := fc.all()
+	slices.SortStableFunc(flashcards, func(a, b Flashcard) int {
+		return strings.Compare(strings.ToLower(a.Term), strings.ToLower(b.Term))
+	})
+	return flashcards
+}
+
+func (fc *Flashcards) Search(query string) []Flashcard {
+	fc.mu.RLock()
+	defer fc.mu.RUnlock()
+
+	query = strings.ToLower(query)
+	var flashcards []Flashcard
+	for _, key := range fc.keys() {
+		flashcard := fc.elements[key]
+		if strings.Contains(strings.ToLower(flashcard.Term), query) ||
+			strings.Contains(strings.ToLower(flashcard.Definition), query) {
+			flashcards = append(flashcards, flashcard)
+		}
+	}
+	return flashcards
+}
+
+func (fc *Flashcards) FilterByTag(tag string) []Flashcard {
+	fc.mu.RLock()
+	defer fc.mu.RUnlock()
+
+	var flashcards []Flashcard
+	for _, key := range fc.keys() {
+		if flashcard := fc.elements[key]; flashcard.HasTag(tag) {
+			flashcards = append(flashcards, flashcard)
+		}
+	}
+	return flashcards
+}
+
+func (fc *Flashcards) Unseen() []Flashcard {
+	fc.mu.RLock()
+	defer fc.mu.RUnlock()
+
+	var flashcards []Flashcard
+	for _, key := range fc.keys() {
+		if flashcard := fc.elements[key]; flashcard.LastSeen.IsZero() {
+			flashcards = append(flashcards, flashcard)
+		}
+	}
+	return flashcards
+}
+
+func (fc *Flashcards) StudyPlan(perTag int) []Flashcard {
+	fc.mu.RLock()
+	defer fc.mu.RUnlock()
+
+	byTag := make(map[string][]Flashcard)
+	var tags []string
+	for _, flashcard := range fc.all() {
+		for _, tag := range flashcard.Tags {
+			if _, seen := byTag[tag]; !seen {
+				tags = append(tags, tag)
+			}
+			if len(byTag[tag]) < perTag {
+				byTag[tag] = append(byTag[tag], flashcard)
+			}
+		}
+	}
+	slices.Sort(tags)
+
+	var plan []Flashcard
+	planned := make(map[string]bool)
+	for i := 0; i < perTag; i++ {
+		for _, tag := range tags {
+			if i >= len(byTag[tag]) {
+				continue
+			}
+			if flashcard := byTag[tag][i]; !planned[flashcard.Term] {
+				planned[flashcard.Term] = true
+				plan = append(plan, flashcard)
+			}
+		}
+	}
+	return plan
+}
+
+func (fc *Flashcards) WeakestTag() (string, float64) {
+	fc.mu.RLock()
+	defer fc.mu.RUnlock()
+
+	correct := make(map[string]int)
+	answers := make(map[string]int)
+	for _, flashcard := range fc.elements {
+		for _, tag := range flashcard.Tags {
+			correct[tag] += flashcard.Correct
+			answers[tag] += flashcard.Correct + flashcard.Mistakes
+		}
+	}
+
+	weakest, weakestAccuracy := "", 0.0
+	for tag, n := range answers {
+		if n == 0 {
+			continue
+		}
+		accuracy := float64(correct[tag]) / float64(n)
+		if weakest == "" || accuracy < weakestAccuracy || accuracy == weakestAccuracy && tag < weakest {
+			weakest, weakestAccuracy = tag, accuracy
+		}
+	}
+	return weakest, weakestAccuracy
+}
+
+func (fc *Flashcards) RenameTag(oldTag, newTag string) int {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+
+	renamed := 0
+	for key, flashcard := range fc.elements {
+		i := slices.Index(flashcard.Tags, oldTag)
+		if i < 0 || oldTag == newTag {
+			continue
+		}
+		tags := slices.Clone(flashcard.Tags)
+		if slices.Contains(tags, newTag) {
+			tags = slices.Delete(tags, i, i+1)
+		} else {
+			tags[i] = newTag
+		}
+		flashcard.Tags = tags
+		fc.elements[key] = flashcard
+		renamed++
+	}
+	if renamed > 0 {
+		fc.dirty = true
+	}
+	return renamed
+}
+
+func (fc *Flashcards) RemoveTag(tag string) int {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+
+	removed := 0
+	for key, flashcard := range fc.elements {
+		if !flashcard.HasTag(tag) {
+			continue
+		}
+		flashcard.Tags = slices.DeleteFunc(slices.Clone(flashcard.Tags), func(t string) bool {
+			return t == tag
+		})
+		fc.elements[key] = flashcard
+		removed++
+	}
+	if removed > 0 {
+		fc.dirty = true
+	}
+	return removed
+}
+
+func (fc *Flashcards) PickDistractors(correct Flashcard, n int) []Flashcard {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+	return fc.pickDistractors(correct, n)
+}
+
+func (fc *Flashcards) pickDistractors(correct Flashcard, n int) []Flashcard {
+	var candidates []Flashcard
+	for _, key := range fc.keys() {
+		if flashcard := fc.elements[key]; flashcard.Term != correct.Term {
+			candidates = append(candidates, flashcard)
+		}
+	}
+	fc.rng.Shuffle(len(candidates), func(i, j int) {
+		candidates[i], candidates[j] = candidates[j], candidates[i]
+	})
+	return candidates[:min(n, len(candidates))]
+}
+
+func (fc *Flashcards) Choices(correct Flashcard, n int) []Flashcard {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+
+	options := append(fc.pickDistractors(correct, n), correct)
+	fc.rng.Shuffle(len(options), func(i, j int) {
+		options[i], options[j] = options[j], options[i]
+	})
+	return options
+}
+
+func (fc *Flashcards) PickRandom(flashcards []Flashcard) (Flashcard, bool) {
+	if len(flashcards) == 0 {
+		return Flashcard{}, false
+	}
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+	return flashcards[fc.rng.Intn(len(flashcards))], true
+}
+
+func (fc *Flashcards) FlipCoin() bool {
+	return fc.chance(0.5)
+}
+
+func (fc *Flashcards) csvDelimiter() rune {
+	if fc.delimiter == 0 {
+		return ','
+	}
+	return fc.delimiter
+}
+
+func (fc *Flashcards) IsDirty() bool {
+	fc.mu.RLock()
+	defer fc.mu.RUnlock()
+	return fc.dirty
+}
+
+func (fc *Flashcards) MarkSaved() {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+	fc.dirty = false
+}
+
+func (fc *Flashcards) ResetCard(term string) bool {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+
+	flashcard, key, exists := fc.getByTerm(term)
+	if !exists {
+		return false
+	}
+	flashcard.Mistakes = 0
+	flashcard.Correct = 0
+	flashcard.Streak = 0
+	fc.elements[key] = flashcard
+	fc.dirty = true
+	return true
+}
+
+func (fc *Flashcards) ResetTag(tag string) int {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+
+	reset := 0
+	for key, flashcard := range fc.elements {
+		if !flashcard.HasTag(tag) {
+			continue
+		}
+		flashcard.Mistakes = 0
+		flashcard.Correct = 0
+		flashcard.Streak = 0
+		fc.elements[key] = flashcard
+		reset++
+	}
+	if reset > 0 {
+		fc.dirty = true
+	}
+	return reset
+}
+
+func (fc *Flashcards) Clear() int {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+
+	removed := len(fc.elements)
+	fc.elements = make(map[int]Flashcard)
+	fc.byTerm = make(map[string]int)
+	fc.nextID = 0
+	if removed > 0 {
+		fc.dirty = true
+	}
+	return removed
+}
+
+func (fc *Flashcards) ResetStats() {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+
+	for i, flashcard := range fc.elements {
+		flashcard.Mistakes = 0
+		flashcard.Correct = 0
+		flashcard.Streak = 0
+		fc.elements[i] = flashcard
+	}
+	fc.dirty = true
+}
+
+func (fc *Flashcards) EasiestCards() []Flashcard {
+	fc.mu.RLock()
+	defer fc.mu.RUnlock()
+
+	var easiestCards []Flashcard
+	for _, flashcard := range fc.all() {
+		if flashcard.Mistakes == 0 && flashcard.Correct > 0 {
+			easiestCards = append(easiestCards, flashcard)
+		}
+	}
+	return easiestCards
+}
+
+func (fc *Flashcards) TopHardest(n int) []Flashcard {
+	if n <= 0 {
+		return nil
+	}
+	fc.mu.RLock()
+	defer fc.mu.RUnlock()
+
+	var flashcards []Flashcard
+	for _, flashcard := range fc.all() {
+		if flashcard.Mistakes > 0 {
+			flashcards = append(flashcards, flashcard)
+		}
+	}
+	sortByMistakes(flashcards)
+	return flashcards[:min(n, len(flashcards))]
+}
+
+func (fc *Flashcards) CardsWithAtLeast(n int) []Flashcard {
+	fc.mu.RLock()
+	defer fc.mu.RUnlock()
+
+	var flashcards []Flashcard
+	for _, flashcard := range fc.all() {
+		if flashcard.Mistakes >= n {
+			flashcards = append(flashcards, flashcard)
+		}
+	}
+	sortByMistakes(flashcards)
+	return flashcards
+}
+
+func (fc *Flashcards) StaleCards(olderThan time.Duration) []Flashcard {
+	fc.mu.RLock()
+	defer fc.mu.RUnlock()
+	return fc.staleCards(fc.now(), olderThan)
+}
+
+func (fc *Flashcards) staleCards(now time.Time, olderThan time.Duration) []Flashcard {
+	var flashcards []Flashcard
+	for _, flashcard := range fc.all() {
+		if flashcard.LastSeen.IsZero() || now.Sub(flashcard.LastSeen) > olderThan {
+			flashcards = append(flashcards, flashcard)
+		}
+	}
+	slices.SortStableFunc(flashcards, func(a, b Flashcard) int {
+		if c := a.LastSeen.Compare(b.LastSeen); c != 0 {
+			return c
+		}
+		return strings.Compare(a.Term, b.Term)
+	})
+	return flashcards
+}
+
+func sortByMistakes(flashcards []Flashcard) {
+	slices.SortStableFunc(flashcards, func(a, b Flashcard) int {
+		if a.Mistakes != b.Mistakes {
+			return b.Mistakes - a.Mistakes
+		}
+		return strings.Compare(a.Term, b.Term)
+	})
+}
+
+func (fc *Flashcards) HardestCards() []Flashcard {
+	fc.mu.RLock()
+	defer fc.mu.RUnlock()
+	return fc.hardestCards()
+}
+
+func (fc *Flashcards) hardestCards() []Flashcard {
+	maxMistakes := 0
+	for _, flashcard := range fc.elements {
+		maxMistakes = max(maxMistakes, flashcard.Mistakes)
+	}
+
+	var hardestCards []Flashcard
+
+	if maxMistakes == 0 {
+		return hardestCards
+	}
+
+	for _, flashcard := range fc.all() {
+		if flashcard.Mistakes == maxMistakes {
+			hardestCards = append(hardestCards, flashcard)
+		}
+	}
+
+	return hardestCards
+}
+
+func (fc *Flashcards) AccuracyByTerm() map[string]float64 {
+	fc.mu.RLock()
+	defer fc.mu.RUnlock()
+
+	accuracy := make(map[string]float64, len(fc.elements))
+	for _, flashcard := range fc.elements {
+		accuracy[flashcard.Term] = flashcard.Accuracy()
+	}
+	return accuracy
+}
+
+type Improvement struct {
+	Term   string
+	Before float64
+	After  float64
+}
+
+func (fc *Flashcards) Improvements(baseline map[string]float64) []Improvement {
+	fc.mu.RLock()
+	defer fc.mu.RUnlock()
+
+	var improvements []Improvement
+	for _, flashcard := range fc.all() {
+		if before, ok := baseline[flashcard.Term]; ok && flashcard.Accuracy() > before {
+			improvements = append(improvements, Improvement{Term: flashcard.Term, Before: before, After: flashcard.Accuracy()})
+		}
+	}
+	slices.SortStableFunc(improvements, func(a, b Improvement) int {
+		return cmp.Compare(b.After-b.Before, a.After-a.Before)
+	})
+	return improvements
+}
+
+type DeckStats struct {
+	Cards             int
+	CardsWithMistakes int
+	TotalMistakes     int
+	AverageMistakes   float64
+}
+
+func (fc *Flashcards) Stats() DeckStats {
+	fc.mu.RLock()
+	defer fc.mu.RUnlock()
+	return fc.stats()
+}
+
+func (fc *Flashcards) stats() DeckStats {
+	stats := DeckStats{Cards: len(fc.elements)}
+	for _, flashcard := range fc.elements {
+		if flashcard.Mistakes > 0 {
+			stats.CardsWithMistakes++
+		}
+		stats.TotalMistakes += flashcard.Mistakes
+	}
+	if stats.Cards > 0 {
+		stats.AverageMistakes = float64(stats.TotalMistakes) / float64(stats.Cards)
+	}
+	return stats
+}
+
+func (fc *Flashcards) MarkSeen(term string) {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+
+	if flashcard, key, exists := fc.getByTerm(term); exists {
+		flashcard.LastSeen = fc.now()
+		fc.elements[key] = flashcard
+		fc.dirty = true
+	}
+}
+
+func (fc *Flashcards) SetMistakes(term string, n int) bool {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+
+	flashcard, key, exists := fc.getByTerm(term)
+	if !exists {
+		return false
+	}
+	flashcard.Mistakes = n
+	fc.elements[key] = flashcard
+	fc.dirty = true
+	return true
+}
+
+func (fc *Flashcards) IncrementCorrect(term string) {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+
+	if flashcard, key, exists := fc.getByTerm(term); exists {
+		flashcard.Correct += 1
+		flashcard.Streak += 1
+		fc.elements[key] = flashcard
+		fc.dirty = true
+	}
+}
+
+func (fc *Flashcards) IncrementMistakes(term string) {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+
+	if flashcard, key, exists := fc.getByTerm(term); exists {
+		flashcard.Mistakes += 1
+		flashcard.Streak = 0
+		fc.elements[key] = flashcard
+		fc.dirty = true
+	}
+}
@@ -0,0 +1,956 @@
+package deck
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+func newDeck(t testing.TB, pairs ...string) *Flashcards {
+	t.Helper()
+	fc := NewFlashcards()
+	for i := 0; i+1 < len(pairs); i += 2 {
+		fc.CreateOrUpdate(Flashcard{Term: pairs[i], Definition: pairs[i+1]})
+	}
+	return fc
+}
+
+func terms(flashcards []Flashcard) []string {
+	var terms []string
+	for _, flashcard := range flashcards {
+		terms = append(terms, flashcard.Term)
+	}
+	return terms
+}
+
+func checkIndex(t *testing.T, fc *Flashcards) {
+	t.Helper()
+	if len(fc.byTerm) != len(fc.elements) {
+		t.Fatalf("index has %d terms, deck has %d cards", len(fc.byTerm), len(fc.elements))
+	}
+	for term, key := range fc.byTerm {
+		if flashcard, exists := fc.elements[key]; !exists || flashcard.Term != term {
+			t.Fatalf("index maps %q to %d, which holds %q", term, key, flashcard.Term)
+		}
+	}
+}
+
+func TestEditRenames(t *testing.T) {
+	fc := newDeck(t, "cat", "кот", "dog", "собака")
+
+	if err := fc.Edit("cat", "kitten", "котёнок", []string{"pets"}); err != nil {
+		t.Fatalf("Edit() = %v", err)
+	}
+	checkIndex(t, fc)
+	if _, exists := fc.GetByTerm("cat"); exists {
+		t.Error("the old term is still found")
+	}
+	flashcard, exists := fc.GetByTerm("kitten")
+	if !exists || flashcard.Definition != "котёнок" || !flashcard.HasTag("pets") {
+		t.Errorf("GetByTerm(kitten) = %+v, %v", flashcard, exists)
+	}
+}
+
+func TestEditKeepsTermAndDefinition(t *testing.T) {
+	fc := newDeck(t, "cat", "кот")
+
+	if err := fc.Edit("cat", "cat", "кот", nil); err != nil {
+		t.Fatalf("Edit() = %v", err)
+	}
+	checkIndex(t, fc)
+}
+
+func TestEditCollisions(t *testing.T) {
+	tests := []struct {
+		name          string
+		term          string
+		newTerm       string
+		newDefinition string
+		want          error
+	}{
+		{"missing card", "cow", "cow", "корова", ErrNoSuchCard},
+		{"term of another card", "cat", "dog", "кот", ErrTermExists},
+		{"definition of another card", "cat", "cat", "собака", ErrDefinitionExists},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fc := newDeck(t, "cat", "кот", "dog", "собака")
+
+			if err := fc.Edit(tt.term, tt.newTerm, tt.newDefinition, nil); !errors.Is(err, tt.want) {
+				t.Fatalf("Edit() = %v, want %v", err, tt.want)
+			}
+			checkIndex(t, fc)
+			if got := terms(fc.All()); fmt.Sprint(got) != "[cat dog]" {
+				t.Errorf("terms = %v, want unchanged", got)
+			}
+			if definition, _ := fc.FindDefinitionByTerm("dog"); definition != "собака" {
+				t.Errorf("dog is now %q", definition)
+			}
+		})
+	}
+}
+
+func BenchmarkLookup(b *testing.B) {
+	fc := NewFlashcards()
+	for i := 0; i < 10000; i++ {
+		fc.CreateOrUpdate(Flashcard{Term: fmt.Sprint("term", i), Definition: fmt.Sprint("definition", i)})
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, exists := fc.GetByTerm(fmt.Sprint("term", i%10000)); !exists {
+			b.Fatal("term not found")
+		}
+	}
+}
+
+func TestTopHardest(t *testing.T) {
+	fc := newDeck(t, "a", "1", "b", "2", "c", "3", "d", "4")
+	fc.SetMistakes("a", 1)
+	fc.SetMistakes("b", 3)
+	fc.SetMistakes("c", 3)
+
+	tests := []struct {
+		n    int
+		want string
+	}{
+		{-1, "[]"},
+		{0, "[]"},
+		{1, "[b]"},
+		{2, "[b c]"},
+		{10, "[b c a]"},
+	}
+	for _, tt := range tests {
+		if got := terms(fc.TopHardest(tt.n)); fmt.Sprint(got) != tt.want {
+			t.Errorf("TopHardest(%d) = %v, want %s", tt.n, got, tt.want)
+		}
+	}
+}
+
+func TestImprovements(t *testing.T) {
+	fc := newDeck(t, "a", "1", "b", "2", "c", "3", "d", "4")
+	fc.IncrementMistakes("a")
+	fc.IncrementMistakes("b")
+	fc.IncrementCorrect("c")
+	baseline := fc.AccuracyByTerm()
+
+	fc.IncrementCorrect("a")
+	fc.IncrementCorrect("b")
+	fc.IncrementCorrect("b")
+	fc.IncrementCorrect("c")
+	fc.IncrementMistakes("d")
+	fc.CreateOrUpdate(Flashcard{Term: "e", Definition: "5", Correct: 1})
+
+	improvements := fc.Improvements(baseline)
+	want := []Improvement{{"b", 0, 2.0 / 3}, {"a", 0, 0.5}}
+	if fmt.Sprint(improvements) != fmt.Sprint(want) {
+		t.Errorf("Improvements() = %v, want %v", improvements, want)
+	}
+}
+
+func TestImprovementsWithoutAnswers(t *testing.T) {
+	fc := newDeck(t, "a", "1")
+	fc.SetMistakes("a", 3)
+	baseline := fc.AccuracyByTerm()
+
+	if improvements := fc.Improvements(baseline); len(improvements) != 0 {
+		t.Errorf("Improvements() = %v, want none", improvements)
+	}
+}
+
+func TestRemoveByTerm(t *testing.T) {
+	for size := 1; size <= 5; size++ {
+		tests := []struct {
+			name string
+			term string
+			want bool
+		}{
+			{"first", "term0", true},
+			{"middle", fmt.Sprint("term", size/2), true},
+			{"last", fmt.Sprint("term", size-1), true},
+			{"nonexistent", "missing", false},
+		}
+		for _, tt := range tests {
+			t.Run(fmt.Sprintf("%s of %d", tt.name, size), func(t *testing.T) {
+				var pairs []string
+				for i := 0; i < size; i++ {
+					pairs = append(pairs, fmt.Sprint("term", i), fmt.Sprint("definition", i))
+				}
+				fc := newDeck(t, pairs...)
+
+				if got := fc.RemoveByTerm(tt.term); got != tt.want {
+					t.Fatalf("RemoveByTerm(%q) = %v, want %v", tt.term, got, tt.want)
+				}
+				checkIndex(t, fc)
+				wantLen := size
+				if tt.want {
+					wantLen--
+				}
+				if fc.Len() != wantLen {
+					t.Errorf("Len() = %d, want %d", fc.Len(), wantLen)
+				}
+				if _, exists := fc.GetByTerm(tt.term); exists {
+					t.Errorf("%q is still found", tt.term)
+				}
+				for i := 0; i < size; i++ {
+					term := fmt.Sprint("term", i)
+					if _, exists := fc.GetByTerm(term); !exists && term != tt.term {
+						t.Errorf("%q is gone", term)
+					}
+				}
+			})
+		}
+	}
+}
+
+func TestCreateAfterRemove(t *testing.T) {
+	fc := newDeck(t, "a", "1", "b", "2", "c", "3")
+	fc.RemoveByTerm("b")
+	fc.CreateOrUpdate(Flashcard{Term: "d", Definition: "4"})
+
+	checkIndex(t, fc)
+	for _, term := range []string{"a", "c", "d"} {
+		if _, exists := fc.GetByTerm(term); !exists {
+			t.Errorf("%q is missing", term)
+		}
+	}
+	if fc.Len() != 3 {
+		t.Errorf("Len() = %d, want 3", fc.Len())
+	}
+}
+
+func TestGetRandomFcEmptyDeck(t *testing.T) {
+	if flashcard, ok := NewFlashcards().GetRandomFc(); ok {
+		t.Errorf("GetRandomFc() = %+v, true on an empty deck", flashcard)
+	}
+}
+
+func TestPickDistractors(t *testing.T) {
+	for size := 1; size <= 6; size++ {
+		var pairs []string
+		for i := 0; i < size; i++ {
+			pairs = append(pairs, fmt.Sprint("term", i), fmt.Sprint("definition", i))
+		}
+		fc := newDeck(t, pairs...)
+		fc.SetSeed(int64(size))
+		correct, _ := fc.GetByTerm("term0")
+
+		for round := 0; round < 20; round++ {
+			distractors := fc.PickDistractors(correct, 3)
+			if want := min(3, size-1); len(distractors) != want {
+				t.Fatalf("deck of %d: got %d distractors, want %d", size, len(distractors), want)
+			}
+			seen := map[string]bool{}
+			for _, distractor := range distractors {
+				if distractor.Term == correct.Term {
+					t.Fatalf("deck of %d: the correct card is a distractor", size)
+				}
+				if seen[distractor.Term] {
+					t.Fatalf("deck of %d: %q is picked twice", size, distractor.Term)
+				}
+				seen[distractor.Term] = true
+			}
+		}
+	}
+}
+
+func TestStats(t *testing.T) {
+	tests := []struct {
+		name     string
+		mistakes []int
+		want     DeckStats
+	}{
+		{"empty", nil, DeckStats{}},
+		{"no mistakes", []int{0, 0}, DeckStats{Cards: 2}},
+		{"one card", []int{4}, DeckStats{1, 1, 4, 4}},
+		{"mixed", []int{0, 1, 2}, DeckStats{3, 2, 3, 1}},
+		{"uneven average", []int{1, 0, 0}, DeckStats{3, 1, 1, 1.0 / 3}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fc := NewFlashcards()
+			for i, mistakes := range tt.mistakes {
+				fc.CreateOrUpdate(Flashcard{Term: fmt.Sprint("term", i), Definition: fmt.Sprint("definition", i), Mistakes: mistakes})
+			}
+			if got := fc.Stats(); got != tt.want {
+				t.Errorf("Stats() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFilterByTag(t *testing.T) {
+	fc := NewFlashcards()
+	fc.CreateOrUpdate(Flashcard{Term: "cat", Definition: "кот", Tags: []string{"pets", "nouns"}})
+	fc.CreateOrUpdate(Flashcard{Term: "run", Definition: "бежать", Tags: []string{"verbs"}})
+	fc.CreateOrUpdate(Flashcard{Term: "dog", Definition: "собака", Tags: []string{"pets"}})
+	fc.CreateOrUpdate(Flashcard{Term: "sun", Definition: "солнце"})
+
+	tests := []struct {
+		tag  string
+		want string
+	}{
+		{"pets", "[cat dog]"},
+		{"verbs", "[run]"},
+		{"Pets", "[]"},
+		{"", "[]"},
+	}
+	for _, tt := range tests {
+		if got := terms(fc.FilterByTag(tt.tag)); fmt.Sprint(got) != tt.want {
+			t.Errorf("FilterByTag(%q) = %v, want %s", tt.tag, got, tt.want)
+		}
+	}
+}
+
+func TestParseTags(t *testing.T) {
+	if got := ParseTags(" pets, nouns,,pets , "); fmt.Sprint(got) != "[pets nouns]" {
+		t.Errorf("ParseTags() = %q, want [pets nouns]", got)
+	}
+}
+
+func TestGetWeightedRandomFc(t *testing.T) {
+	fc := newDeck(t, "hard", "1", "easy", "2")
+	fc.SetMistakes("hard", 9)
+	fc.SetSeed(1)
+
+	counts := map[string]int{}
+	for i := 0; i < 11000; i++ {
+		flashcard, _ := fc.GetWeightedRandomFc()
+		counts[flashcard.Term]++
+	}
+	if counts["hard"] < 9500 || counts["hard"] > 10500 {
+		t.Errorf("picked hard %d and easy %d times, want about 10000 and 1000", counts["hard"], counts["easy"])
+	}
+}
+
+func TestGetRandomFcSeeded(t *testing.T) {
+	sequence := func(seed int64) []string {
+		fc := newDeck(t, "a", "1", "b", "2", "c", "3", "d", "4")
+		fc.SetSeed(seed)
+		var picked []string
+		for i := 0; i < 20; i++ {
+			flashcard, _ := fc.GetRandomFc()
+			picked = append(picked, flashcard.Term)
+		}
+		return picked
+	}
+
+	first := sequence(42)
+	if second := sequence(42); fmt.Sprint(first) != fmt.Sprint(second) {
+		t.Errorf("seed 42 gave %v, then %v", first, second)
+	}
+	if other := sequence(7); fmt.Sprint(first) == fmt.Sprint(other) {
+		t.Errorf("seeds 42 and 7 both gave %v", first)
+	}
+}
+
+func TestSearch(t *testing.T) {
+	fc := newDeck(t, "Cat", "кот", "category", "категория", "dog", "собака", "hot dog", "хот-дог")
+
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{"cat", "[Cat category]"},
+		{"DOG", "[dog hot dog]"},
+		{"кот", "[Cat]"},
+		{"КАТ", "[category]"},
+		{"bird", "[]"},
+		{"", "[Cat category dog hot dog]"},
+	}
+	for _, tt := range tests {
+		if got := terms(fc.Search(tt.query)); fmt.Sprint(got) != tt.want {
+			t.Errorf("Search(%q) = %v, want %s", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestSortedByTerm(t *testing.T) {
+	fc := newDeck(t, "banana", "1", "Apple", "2", "cherry", "3", "apple", "4", "Banana", "5")
+
+	if got := terms(fc.SortedByTerm()); fmt.Sprint(got) != "[Apple apple banana Banana cherry]" {
+		t.Errorf("SortedByTerm() = %v, want ties in insertion order", got)
+	}
+	if got := terms(fc.All()); fmt.Sprint(got) != "[banana Apple cherry apple Banana]" {
+		t.Errorf("All() = %v, want the stored order untouched", got)
+	}
+}
+
+func TestMergeSumsCounts(t *testing.T) {
+	fc := NewFlashcards()
+	fc.CreateOrUpdate(Flashcard{Term: "cat", Definition: "кот", Mistakes: 2, Correct: 1, Tags: []string{"pets"}})
+
+	if !fc.Merge(Flashcard{Term: "cat", Definition: "кошка", Mistakes: 3, Correct: 4, Tags: []string{"pets", "nouns"}}) {
+		t.Error("Merge() of an existing term = false")
+	}
+	if fc.Merge(Flashcard{Term: "dog", Definition: "собака", Mistakes: 1}) {
+		t.Error("Merge() of a new term = true")
+	}
+	checkIndex(t, fc)
+
+	cat, _ := fc.GetByTerm("cat")
+	if cat.Definition != "кот" || cat.Mistakes != 5 || cat.Correct != 5 || fmt.Sprint(cat.Tags) != "[pets nouns]" {
+		t.Errorf("cat = %+v, want the first definition and summed counts", cat)
+	}
+	if dog, _ := fc.GetByTerm("dog"); dog.Mistakes != 1 {
+		t.Errorf("dog = %+v", dog)
+	}
+}
+
+func TestAccuracy(t *testing.T) {
+	tests := []struct {
+		correct, mistakes int
+		want              float64
+	}{
+		{0, 0, 0},
+		{3, 0, 1},
+		{0, 2, 0},
+		{1, 3, 0.25},
+		{2, 1, 2.0 / 3},
+	}
+	for _, tt := range tests {
+		flashcard := Flashcard{Correct: tt.correct, Mistakes: tt.mistakes}
+		if got := flashcard.Accuracy(); got != tt.want {
+			t.Errorf("Accuracy() with %d correct and %d mistakes = %v, want %v", tt.correct, tt.mistakes, got, tt.want)
+		}
+	}
+}
+
+func TestConcurrentAccess(t *testing.T) {
+	fc := newDeck(t, "cat", "кот")
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				term := fmt.Sprint("term", i, "-", j)
+				fc.CreateOrUpdate(Flashcard{Term: term, Definition: fmt.Sprint("definition", i, "-", j)})
+				if flashcard, ok := fc.GetRandomFc(); ok {
+					fc.IncrementMistakes(flashcard.Term)
+					fc.FindDefinitionByTerm(flashcard.Term)
+				}
+				fc.HardestCards()
+				fc.Stats()
+				if j%2 == 0 {
+					fc.RemoveByTerm(term)
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	checkIndex(t, fc)
+	if fc.Len() != 1+8*50 {
+		t.Errorf("Len() = %d, want %d", fc.Len(), 1+8*50)
+	}
+}
+
+func TestSetMistakes(t *testing.T) {
+	fc := newDeck(t, "cat", "кот")
+	fc.MarkSaved()
+
+	if !fc.SetMistakes("cat", 7) {
+		t.Error("SetMistakes(cat) = false")
+	}
+	if cat, _ := fc.GetByTerm("cat"); cat.Mistakes != 7 || !fc.IsDirty() {
+		t.Errorf("cat = %+v, dirty = %v", cat, fc.IsDirty())
+	}
+	if fc.SetMistakes("dog", 1) {
+		t.Error("SetMistakes(dog) = true for a missing card")
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		term, definition string
+		want             error
+	}{
+		{"cat", "кот", nil},
+		{" cat ", "кот", nil},
+		{"", "кот", ErrEmptyTerm},
+		{" \t", "кот", ErrEmptyTerm},
+		{"cat", "", ErrEmptyDefinition},
+		{"cat", "\n ", ErrEmptyDefinition},
+		{"", "", ErrEmptyTerm},
+	}
+	for _, tt := range tests {
+		if err := Validate(tt.term, tt.definition); !errors.Is(err, tt.want) {
+			t.Errorf("Validate(%q, %q) = %v, want %v", tt.term, tt.definition, err, tt.want)
+		}
+	}
+}
+
+func TestCopy(t *testing.T) {
+	tests := []struct {
+		name    string
+		term    string
+		newTerm string
+		want    bool
+		wantLen int
+	}{
+		{"success", "cat", "kitty", true, 3},
+		{"missing source", "cow", "calf", false, 2},
+		{"colliding target", "cat", "dog", false, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fc := NewFlashcards()
+			fc.CreateOrUpdate(Flashcard{Term: "cat", Definition: "кот", Mistakes: 4, Tags: []string{"pets"}})
+			fc.CreateOrUpdate(Flashcard{Term: "dog", Definition: "собака"})
+
+			if got := fc.Copy(tt.term, tt.newTerm); got != tt.want {
+				t.Fatalf("Copy(%q, %q) = %v, want %v", tt.term, tt.newTerm, got, tt.want)
+			}
+			checkIndex(t, fc)
+			if fc.Len() != tt.wantLen {
+				t.Errorf("Len() = %d, want %d", fc.Len(), tt.wantLen)
+			}
+			if dog, _ := fc.GetByTerm("dog"); dog.Definition != "собака" {
+				t.Errorf("dog = %+v", dog)
+			}
+		})
+	}
+}
+
+func TestCopyResetsMistakes(t *testing.T) {
+	fc := NewFlashcards()
+	fc.CreateOrUpdate(Flashcard{Term: "cat", Definition: "кот", Mistakes: 4, Tags: []string{"pets"}})
+	fc.Copy("cat", "kitty")
+
+	kitty, _ := fc.GetByTerm("kitty")
+	if kitty.Definition != "кот" || kitty.Mistakes != 0 || !kitty.HasTag("pets") {
+		t.Errorf("kitty = %+v, want the definition and tags without mistakes", kitty)
+	}
+	if cat, _ := fc.GetByTerm("cat"); cat.Mistakes != 4 {
+		t.Errorf("cat = %+v, want it untouched", cat)
+	}
+}
+
+func TestDeduplicate(t *testing.T) {
+	fc := NewFlashcards()
+	fc.CreateOrUpdate(Flashcard{Term: "cat", Definition: "кот", Mistakes: 1, Tags: []string{"pets"}})
+	fc.CreateOrUpdate(Flashcard{Term: "dog", Definition: "собака", Mistakes: 2})
+	fc.CreateOrUpdate(Flashcard{Term: "hound", Definition: "собака", Mistakes: 3, Tags: []string{"hunting"}})
+	fc.CreateOrUpdate(Flashcard{Term: "cow", Definition: "корова"})
+	fc.elements[fc.nextID] = Flashcard{Term: "cat", Definition: "кошка", Mistakes: 4}
+	fc.nextID++
+	fc.reindex()
+
+	if removed := fc.Deduplicate(); removed != 2 {
+		t.Errorf("Deduplicate() = %d, want 2", removed)
+	}
+	checkIndex(t, fc)
+	if got := terms(fc.All()); fmt.Sprint(got) != "[cat dog cow]" {
+		t.Errorf("terms = %v, want the first of each duplicate kept", got)
+	}
+	if cat, _ := fc.GetByTerm("cat"); cat.Definition != "кот" || cat.Mistakes != 5 {
+		t.Errorf("cat = %+v, want 5 mistakes", cat)
+	}
+	if dog, _ := fc.GetByTerm("dog"); dog.Mistakes != 5 || fmt.Sprint(dog.Tags) != "[hunting]" {
+		t.Errorf("dog = %+v, want 5 mistakes and the hunting tag", dog)
+	}
+	if removed := fc.Deduplicate(); removed != 0 {
+		t.Errorf("second Deduplicate() = %d, want 0", removed)
+	}
+}
+
+func TestSwap(t *testing.T) {
+	tests := []struct {
+		name  string
+		pairs []string
+		want  error
+	}{
+		{"clean", []string{"cat", "кот", "dog", "собака"}, nil},
+		{"palindrome", []string{"cat", "cat"}, nil},
+		{"definition is another term", []string{"cat", "кот", "кот", "tomcat"}, ErrTermExists},
+		{"term is another definition", []string{"cat", "кот", "kitty", "cat"}, ErrDefinitionExists},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fc := newDeck(t, tt.pairs...)
+			fc.SetMistakes("cat", 2)
+			before := fmt.Sprint(fc.All())
+
+			if err := fc.Swap("cat"); !errors.Is(err, tt.want) {
+				t.Fatalf("Swap() = %v, want %v", err, tt.want)
+			}
+			checkIndex(t, fc)
+			if tt.want != nil {
+				if after := fmt.Sprint(fc.All()); after != before {
+					t.Errorf("deck = %s, want it unchanged", after)
+				}
+				return
+			}
+			swapped, exists := fc.GetByTerm(tt.pairs[1])
+			if !exists || swapped.Definition != "cat" || swapped.Mistakes != 2 {
+				t.Errorf("GetByTerm(%q) = %+v, %v", tt.pairs[1], swapped, exists)
+			}
+		})
+	}
+}
+
+func TestSwapMissingCard(t *testing.T) {
+	if err := newDeck(t, "cat", "кот").Swap("dog"); !errors.Is(err, ErrNoSuchCard) {
+		t.Errorf("Swap(dog) = %v, want %v", err, ErrNoSuchCard)
+	}
+}
+
+func TestAccessors(t *testing.T) {
+	fc := newDeck(t, "cat", "кот", "dog", "собака")
+	fc.IncrementMistakes("cat")
+	fc.IncrementMistakes("cow")
+
+	if cat, exists := fc.GetByTerm("cat"); !exists || cat.Definition != "кот" || cat.Mistakes != 1 {
+		t.Errorf("GetByTerm(cat) = %+v, %v", cat, exists)
+	}
+	if flashcard, exists := fc.GetByTerm("cow"); exists || flashcard.Term != "" {
+		t.Errorf("GetByTerm(cow) = %+v, %v", flashcard, exists)
+	}
+	if definition, exists := fc.FindDefinitionByTerm("dog"); !exists || definition != "собака" {
+		t.Errorf("FindDefinitionByTerm(dog) = %q, %v", definition, exists)
+	}
+	if _, exists := fc.FindDefinitionByTerm("Dog"); exists {
+		t.Error("FindDefinitionByTerm(Dog) found a card")
+	}
+	if term, exists := fc.FindTermByDefinition("кот"); !exists || term != "cat" {
+		t.Errorf("FindTermByDefinition(кот) = %q, %v", term, exists)
+	}
+	if _, exists := fc.FindTermByDefinition("корова"); exists {
+		t.Error("FindTermByDefinition(корова) found a card")
+	}
+	if fc.Len() != 2 {
+		t.Errorf("Len() = %d, want IncrementMistakes(cow) not to add a card", fc.Len())
+	}
+}
+
+func TestStreak(t *testing.T) {
+	fc := newDeck(t, "cat", "кот")
+
+	steps := []struct {
+		correct bool
+		want    int
+	}{
+		{true, 1},
+		{true, 2},
+		{true, 3},
+		{false, 0},
+		{false, 0},
+		{true, 1},
+	}
+	for i, step := range steps {
+		if step.correct {
+			fc.IncrementCorrect("cat")
+		} else {
+			fc.IncrementMistakes("cat")
+		}
+		if cat, _ := fc.GetByTerm("cat"); cat.Streak != step.want {
+			t.Fatalf("after answer %d the streak is %d, want %d", i+1, cat.Streak, step.want)
+		}
+	}
+	if cat, _ := fc.GetByTerm("cat"); cat.Correct != 4 || cat.Mistakes != 2 {
+		t.Errorf("cat = %+v, want 4 correct and 2 mistakes", cat)
+	}
+}
+
+func TestUnseen(t *testing.T) {
+	fc := newDeck(t, "a", "1", "b", "2", "c", "3", "d", "4")
+	fc.MarkSeen("b")
+	fc.MarkSeen("d")
+
+	if got := terms(fc.Unseen()); fmt.Sprint(got) != "[a c]" {
+		t.Errorf("Unseen() = %v, want [a c]", got)
+	}
+	fc.MarkSeen("a")
+	fc.MarkSeen("c")
+	if got := fc.Unseen(); len(got) != 0 {
+		t.Errorf("Unseen() = %v, want none", terms(got))
+	}
+}
+
+func TestClear(t *testing.T) {
+	fc := newDeck(t, "cat", "кот", "dog", "собака", "cow", "корова")
+	fc.MarkSaved()
+
+	if removed := fc.Clear(); removed != 3 {
+		t.Errorf("Clear() = %d, want 3", removed)
+	}
+	checkIndex(t, fc)
+	if fc.Len() != 0 || !fc.IsDirty() {
+		t.Errorf("Len() = %d, dirty = %v, want an empty dirty deck", fc.Len(), fc.IsDirty())
+	}
+	if _, exists := fc.GetByTerm("cat"); exists {
+		t.Error("cat is still found")
+	}
+	fc.CreateOrUpdate(Flashcard{Term: "cat", Definition: "кошка"})
+	if definition, _ := fc.FindDefinitionByTerm("cat"); definition != "кошка" || fc.Len() != 1 {
+		t.Errorf("after re-adding cat = %q, Len() = %d", definition, fc.Len())
+	}
+
+	empty := NewFlashcards()
+	if removed := empty.Clear(); removed != 0 || empty.IsDirty() {
+		t.Errorf("Clear() of an empty deck = %d, dirty = %v", removed, empty.IsDirty())
+	}
+}
+
+func TestStudyPlan(t *testing.T) {
+	fc := NewFlashcards()
+	for _, flashcard := range []Flashcard{
+		{Term: "run", Definition: "бежать", Tags: []string{"verbs"}},
+		{Term: "cat", Definition: "кот", Tags: []string{"nouns"}},
+		{Term: "go", Definition: "идти", Tags: []string{"verbs"}},
+		{Term: "dog", Definition: "собака", Tags: []string{"nouns", "pets"}},
+		{Term: "eat", Definition: "есть", Tags: []string{"verbs"}},
+		{Term: "sun", Definition: "солнце"},
+	} {
+		fc.CreateOrUpdate(flashcard)
+	}
+
+	tests := []struct {
+		perTag int
+		want   string
+	}{
+		{0, "[]"},
+		{1, "[cat dog run]"},
+		{2, "[cat dog run go]"},
+		{5, "[cat dog run go eat]"},
+	}
+	for _, tt := range tests {
+		if got := terms(fc.StudyPlan(tt.perTag)); fmt.Sprint(got) != tt.want {
+			t.Errorf("StudyPlan(%d) = %v, want %s", tt.perTag, got, tt.want)
+		}
+	}
+}
+
+func TestWeakestTag(t *testing.T) {
+	fc := NewFlashcards()
+	for _, flashcard := range []Flashcard{
+		{Term: "run", Definition: "бежать", Tags: []string{"verbs"}, Correct: 3, Mistakes: 2},
+		{Term: "go", Definition: "идти", Tags: []string{"verbs"}, Correct: 2, Mistakes: 1},
+		{Term: "cat", Definition: "кот", Tags: []string{"nouns", "pets"}, Correct: 9, Mistakes: 1},
+		{Term: "dog", Definition: "собака", Tags: []string{"pets"}, Correct: 1, Mistakes: 1},
+		{Term: "new", Definition: "новый", Tags: []string{"adjectives"}},
+		{Term: "sun", Definition: "солнце", Mistakes: 10},
+	} {
+		fc.CreateOrUpdate(flashcard)
+	}
+
+	if tag, accuracy := fc.WeakestTag(); tag != "verbs" || accuracy != 5.0/8 {
+		t.Errorf("WeakestTag() = %q, %v, want verbs, 0.625", tag, accuracy)
+	}
+	fc.SetMistakes("dog", 9)
+	if tag, accuracy := fc.WeakestTag(); tag != "pets" || accuracy != 0.5 {
+		t.Errorf("WeakestTag() = %q, %v, want pets, 0.5", tag, accuracy)
+	}
+}
+
+func TestWeakestTagWithoutAnswers(t *testing.T) {
+	fc := NewFlashcards()
+	fc.CreateOrUpdate(Flashcard{Term: "new", Definition: "новый", Tags: []string{"adjectives"}})
+	fc.CreateOrUpdate(Flashcard{Term: "sun", Definition: "солнце", Mistakes: 3})
+
+	if tag, accuracy := fc.WeakestTag(); tag != "" || accuracy != 0 {
+		t.Errorf("WeakestTag() = %q, %v, want no tag", tag, accuracy)
+	}
+}
+
+func TestFindAllTermsByDefinition(t *testing.T) {
+	fc := newDeck(t, "dog", "собака", "cat", "кот", "hound", "собака")
+
+	if got := fc.FindAllTermsByDefinition("собака"); fmt.Sprint(got) != "[dog hound]" {
+		t.Errorf("FindAllTermsByDefinition(собака) = %v, want [dog hound]", got)
+	}
+	if got := fc.FindAllTermsByDefinition("корова"); len(got) != 0 {
+		t.Errorf("FindAllTermsByDefinition(корова) = %v, want none", got)
+	}
+}
+
+func TestEasiestCards(t *testing.T) {
+	fc := NewFlashcards()
+	for _, flashcard := range []Flashcard{
+		{Term: "never", Definition: "никогда"},
+		{Term: "known", Definition: "известно", Correct: 3},
+		{Term: "shaky", Definition: "шатко", Correct: 5, Mistakes: 1},
+		{Term: "missed", Definition: "пропущено", Mistakes: 2},
+		{Term: "once", Definition: "однажды", Correct: 1},
+	} {
+		fc.CreateOrUpdate(flashcard)
+	}
+
+	if got := terms(fc.EasiestCards()); fmt.Sprint(got) != "[known once]" {
+		t.Errorf("EasiestCards() = %v, want the answered cards without mistakes", got)
+	}
+	fc.IncrementMistakes("once")
+	if got := terms(fc.EasiestCards()); fmt.Sprint(got) != "[known]" {
+		t.Errorf("after a mistake EasiestCards() = %v, want [known]", got)
+	}
+}
+
+func tagsByTerm(fc *Flashcards) string {
+	var tags []string
+	for _, flashcard := range fc.All() {
+		tags = append(tags, fmt.Sprintf("%s:%v", flashcard.Term, flashcard.Tags))
+	}
+	return fmt.Sprint(tags)
+}
+
+func tagRenameDeck() *Flashcards {
+	fc := NewFlashcards()
+	fc.CreateOrUpdate(Flashcard{Term: "run", Definition: "бежать", Tags: []string{"verbz", "motion"}})
+	fc.CreateOrUpdate(Flashcard{Term: "go", Definition: "идти", Tags: []string{"verbz", "verbs"}})
+	fc.CreateOrUpdate(Flashcard{Term: "eat", Definition: "есть", Tags: []string{"verbs"}})
+	fc.CreateOrUpdate(Flashcard{Term: "cat", Definition: "кот"})
+	return fc
+}
+
+func TestRenameTag(t *testing.T) {
+	fc := tagRenameDeck()
+	fc.MarkSaved()
+
+	if renamed := fc.RenameTag("verbz", "verbs"); renamed != 2 {
+		t.Errorf("RenameTag() = %d, want 2", renamed)
+	}
+	if got := tagsByTerm(fc); got != "[run:[verbs motion] go:[verbs] eat:[verbs] cat:[]]" {
+		t.Errorf("tags = %s", got)
+	}
+	if !fc.IsDirty() {
+		t.Error("not dirty after a rename")
+	}
+	if renamed := fc.RenameTag("verbs", "verbs"); renamed != 0 {
+		t.Errorf("RenameTag() to the same tag = %d, want 0", renamed)
+	}
+	if renamed := fc.RenameTag("nouns", "things"); renamed != 0 {
+		t.Errorf("RenameTag() of a missing tag = %d, want 0", renamed)
+	}
+}
+
+func TestRemoveTag(t *testing.T) {
+	fc := tagRenameDeck()
+	snapshot := fc.Snapshot()
+
+	if removed := fc.RemoveTag("verbz"); removed != 2 {
+		t.Errorf("RemoveTag() = %d, want 2", removed)
+	}
+	if got := tagsByTerm(fc); got != "[run:[motion] go:[verbs] eat:[verbs] cat:[]]" {
+		t.Errorf("tags = %s", got)
+	}
+	fc.Restore(snapshot)
+	if got := tagsByTerm(fc); got != "[run:[verbz motion] go:[verbz verbs] eat:[verbs] cat:[]]" {
+		t.Errorf("restored tags = %s, want the snapshot unaffected by the removal", got)
+	}
+}
+
+func TestCardsWithAtLeast(t *testing.T) {
+	fc := newDeck(t, "a", "1", "b", "2", "c", "3", "d", "4")
+	fc.SetMistakes("a", 1)
+	fc.SetMistakes("b", 3)
+	fc.SetMistakes("d", 3)
+
+	tests := []struct {
+		n    int
+		want []string
+	}{
+		{-1, []string{"b", "d", "a", "c"}},
+		{0, []string{"b", "d", "a", "c"}},
+		{1, []string{"b", "d", "a"}},
+		{3, []string{"b", "d"}},
+		{4, nil},
+	}
+	for _, tt := range tests {
+		if got := terms(fc.CardsWithAtLeast(tt.n)); !slices.Equal(got, tt.want) {
+			t.Errorf("CardsWithAtLeast(%d) = %v, want %v", tt.n, got, tt.want)
+		}
+	}
+}
+
+func TestStaleCards(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	fc := NewFlashcards()
+	fc.SetClock(func() time.Time { return now })
+	fc.CreateOrUpdate(Flashcard{Term: "fresh", Definition: "1", LastSeen: now.Add(-time.Hour)})
+	fc.CreateOrUpdate(Flashcard{Term: "old", Definition: "2", LastSeen: now.Add(-72 * time.Hour)})
+	fc.CreateOrUpdate(Flashcard{Term: "older", Definition: "3", LastSeen: now.Add(-96 * time.Hour)})
+	fc.CreateOrUpdate(Flashcard{Term: "never", Definition: "4"})
+
+	if got, want := terms(fc.StaleCards(48*time.Hour)), []string{"never", "older", "old"}; !slices.Equal(got, want) {
+		t.Errorf("StaleCards(48h) = %v, want %v", got, want)
+	}
+	if got, want := terms(fc.StaleCards(80*time.Hour)), []string{"never", "older"}; !slices.Equal(got, want) {
+		t.Errorf("StaleCards(80h) = %v, want %v", got, want)
+	}
+	now = now.Add(24 * time.Hour)
+	if got, want := terms(fc.StaleCards(48*time.Hour)), []string{"never", "older", "old"}; !slices.Equal(got, want) {
+		t.Errorf("StaleCards(48h) a day later = %v, want %v", got, want)
+	}
+	if got, want := terms(fc.StaleCards(0)), []string{"never", "older", "old", "fresh"}; !slices.Equal(got, want) {
+		t.Errorf("StaleCards(0) = %v, want %v", got, want)
+	}
+}
+
+func TestMarkSeenUsesClock(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	fc := newDeck(t, "cat", "кот", "dog", "собака")
+	fc.SetClock(func() time.Time { return now })
+	fc.MarkSaved()
+
+	fc.MarkSeen("cat")
+	if cat, _ := fc.GetByTerm("cat"); !cat.LastSeen.Equal(now) {
+		t.Errorf("cat was last seen at %v, want %v", cat.LastSeen, now)
+	}
+	if dog, _ := fc.GetByTerm("dog"); !dog.LastSeen.IsZero() {
+		t.Errorf("dog was last seen at %v, want never", dog.LastSeen)
+	}
+	if !fc.IsDirty() {
+		t.Error("MarkSeen() left the deck clean")
+	}
+	fc.MarkSeen("cow")
+	if fc.Len() != 2 {
+		t.Errorf("MarkSeen(cow) changed the deck to %d cards", fc.Len())
+	}
+}
+
+func TestResetCardAndTag(t *testing.T) {
+	studied := func() *Flashcards {
+		fc := NewFlashcards()
+		fc.CreateOrUpdate(Flashcard{Term: "run", Definition: "бежать", Tags: []string{"verbs"}, Mistakes: 3, Correct: 2, Streak: 1})
+		fc.CreateOrUpdate(Flashcard{Term: "go", Definition: "идти", Tags: []string{"verbs"}, Mistakes: 1, Correct: 4, Streak: 2})
+		fc.CreateOrUpdate(Flashcard{Term: "cat", Definition: "кот", Tags: []string{"pets"}, Mistakes: 5, Correct: 1, Streak: 1})
+		fc.MarkSaved()
+		return fc
+	}
+	check := func(t *testing.T, fc *Flashcards, term string, mistakes, correct, streak int) {
+		t.Helper()
+		if flashcard, _ := fc.GetByTerm(term); flashcard.Mistakes != mistakes || flashcard.Correct != correct || flashcard.Streak != streak {
+			t.Errorf("%s = %+v, want %d mistakes, %d correct, streak %d", term, flashcard, mistakes, correct, streak)
+		}
+	}
+
+	t.Run("card", func(t *testing.T) {
+		fc := studied()
+		if !fc.ResetCard("run") || !fc.IsDirty() {
+			t.Fatal("ResetCard(run) did not reset the card")
+		}
+		check(t, fc, "run", 0, 0, 0)
+		check(t, fc, "go", 1, 4, 2)
+		check(t, fc, "cat", 5, 1, 1)
+		if fc.ResetCard("cow") {
+			t.Error("ResetCard(cow) = true for a missing card")
+		}
+	})
+	t.Run("tag", func(t *testing.T) {
+		fc := studied()
+		if n := fc.ResetTag("verbs"); n != 2 || !fc.IsDirty() {
+			t.Fatalf("ResetTag(verbs) = %d, want 2", n)
+		}
+		check(t, fc, "run", 0, 0, 0)
+		check(t, fc, "go", 0, 0, 0)
+		check(t, fc, "cat", 5, 1, 1)
+		fc.MarkSaved()
+		if n := fc.ResetTag("birds"); n != 0 || fc.IsDirty() {
+			t.Errorf("ResetTag(birds) = %d, dirty %v, want 0 and a clean deck", n, fc.IsDirty())
+		}
+	})
+}
@@ -0,0 +1,40 @@
+package deck
+
+import "slices"
+
+type Scheduler struct {
+	fc      *Flashcards
+	pick    func() (Flashcard, bool)
+	requeue float64
+	missed  []string
+}
+
+func NewScheduler(fc *Flashcards, pick func() (Flashcard, bool), requeue float64) *Scheduler {
+	return &Scheduler{fc: fc, pick: pick, requeue: requeue}
+}
+
+func (s *Scheduler) Next() (Flashcard, bool) {
+	if s.requeue > 0 && len(s.missed) > 0 && s.fc.chance(s.requeue) {
+		term := s.missed[0]
+		s.missed = s.missed[1:]
+		if flashcard, exists := s.fc.GetByTerm(term); exists {
+			return flashcard, true
+		}
+	}
+	return s.pick()
+}
+
+func (s *Scheduler) Record(term string, correct bool) {
+	s.missed = slices.DeleteFunc(s.missed, func(missed string) bool {
+		return missed == term
+	})
+	if !correct {
+		s.missed = append(s.missed, term)
+	}
+}
+
+func (fc *Flashcards) chance(p float64) bool {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+	return fc.rng.Float64() < p
+}
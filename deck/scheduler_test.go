@@ -0,0 +1,58 @@
+package deck
+
+import (
+	"fmt"
+	"testing"
+)
+
+func reappearances(t *testing.T, requeue float64) int {
+	t.Helper()
+	fc := NewFlashcards()
+	for i := 0; i < 20; i++ {
+		fc.CreateOrUpdate(Flashcard{Term: fmt.Sprint("term", i), Definition: fmt.Sprint("definition", i)})
+	}
+	fc.SetSeed(1)
+
+	reappeared := 0
+	for trial := 0; trial < 500; trial++ {
+		scheduler := NewScheduler(fc, fc.GetRandomFc, requeue)
+		scheduler.Record("term0", false)
+		for i := 0; i < 3; i++ {
+			flashcard, _ := scheduler.Next()
+			if flashcard.Term == "term0" {
+				reappeared++
+				break
+			}
+			scheduler.Record(flashcard.Term, true)
+		}
+	}
+	return reappeared
+}
+
+func TestSchedulerRequeuesMisses(t *testing.T) {
+	base := reappearances(t, 0)
+	requeued := reappearances(t, 0.5)
+	if base > 100 {
+		t.Errorf("without requeue the missed card came back in %d of 500 trials, want about 71", base)
+	}
+	if requeued < 400 {
+		t.Errorf("with requeue 0.5 the missed card came back in %d of 500 trials, want about 440", requeued)
+	}
+}
+
+func TestSchedulerForgetsAnsweredCards(t *testing.T) {
+	fc := newDeck(t, "a", "1", "b", "2")
+	scheduler := NewScheduler(fc, func() (Flashcard, bool) { return Flashcard{Term: "picked"}, true }, 1)
+
+	scheduler.Record("a", false)
+	scheduler.Record("a", true)
+	if flashcard, _ := scheduler.Next(); flashcard.Term != "picked" {
+		t.Errorf("Next() = %q, want a card answered right not requeued", flashcard.Term)
+	}
+
+	scheduler.Record("a", false)
+	fc.RemoveByTerm("a")
+	if flashcard, _ := scheduler.Next(); flashcard.Term != "picked" {
+		t.Errorf("Next() = %q, want a removed card skipped", flashcard.Term)
+	}
+}
@@ -2,380 +2,1932 @@ package main
 
 import (
 	"bufio"
-	"encoding/csv"
+	"errors"
 	"flag"
 	"fmt"
-	"log"
-	"math/rand"
+	"io"
 	"os"
 	"strconv"
 	"strings"
+	"time"
+	"unicode/utf8"
+
+	"flashcards/deck"
+)
+
+var version = "dev"
+
+type LoggingPrinter struct {
+	logBuilder *strings.Builder
+	out        io.Writer
+	quiet      bool
+	color      bool
+	messages   map[message]string
+}
+
+func (lp *LoggingPrinter) write(line string) {
+	lp.logBuilder.WriteString(line)
+	lp.print(line)
+}
+
+func (lp *LoggingPrinter) print(line string) {
+	if lp.out != nil {
+		fmt.Fprint(lp.out, line)
+	} else {
+		fmt.Print(line)
+	}
+}
+
+func (lp *LoggingPrinter) Printf(format string, a ...any) {
+	lp.write(fmt.Sprintf(format, a...))
+}
+
+func (lp *LoggingPrinter) Println(a ...any) {
+	lp.write(fmt.Sprintln(a...))
+}
+
+func (lp *LoggingPrinter) Prompt(a ...any) {
+	line := fmt.Sprintln(a...)
+	if lp.quiet {
+		lp.logBuilder.WriteString(line)
+		return
+	}
+	lp.write(line)
+}
+
+func (lp *LoggingPrinter) text(m message, a ...any) string {
+	format, ok := lp.messages[m]
+	if !ok {
+		format = messages[defaultLanguage][m]
+	}
+	return fmt.Sprintf(format, a...)
+}
+
+func (lp *LoggingPrinter) card(flashcard deck.Flashcard) string {
+	return lp.text(msgCard, flashcard.Term, flashcard.Definition, flashcard.Mistakes)
+}
+
+func (lp *LoggingPrinter) Say(m message, a ...any) {
+	lp.Println(lp.text(m, a...))
+}
+
+const (
+	colorGreen = "\x1b[32m"
+	colorRed   = "\x1b[31m"
+	colorReset = "\x1b[0m"
+)
+
+func (lp *LoggingPrinter) sayColored(color string, m message, a ...any) {
+	line := lp.text(m, a...)
+	if !lp.color {
+		lp.Println(line)
+		return
+	}
+	lp.logBuilder.WriteString(line + "\n")
+	lp.print(color + line + colorReset + "\n")
+}
+
+func (lp *LoggingPrinter) SayCorrect(m message, a ...any) {
+	lp.sayColored(colorGreen, m, a...)
+}
+
+func (lp *LoggingPrinter) SayWrong(m message, a ...any) {
+	lp.sayColored(colorRed, m, a...)
+}
+
+func (lp *LoggingPrinter) Ask(m message, a ...any) {
+	lp.Prompt(lp.text(m, a...))
+}
+
+func (lp *LoggingPrinter) errorText(err error) string {
+	switch {
+	case errors.Is(err, deck.ErrEmptyTerm):
+		return lp.text(msgTermIsEmpty)
+	case errors.Is(err, deck.ErrEmptyDefinition):
+		return lp.text(msgDefinitionIsEmpty)
+	case errors.Is(err, deck.ErrNoSuchCard):
+		return lp.text(msgNoSuchCardError)
+	case errors.Is(err, deck.ErrTermExists):
+		return lp.text(msgTermExistsError)
+	case errors.Is(err, deck.ErrDefinitionExists):
+		return lp.text(msgDefinitionExistsError)
+	case errors.Is(err, deck.ErrTooFewFields):
+		return lp.text(msgTooFewFieldsError)
+	case errors.Is(err, deck.ErrInvalidMistakes):
+		return lp.text(msgInvalidMistakesError)
+	case errors.Is(err, deck.ErrInvalidLastSeen):
+		return lp.text(msgInvalidLastSeenError)
+	case errors.Is(err, deck.ErrInvalidCorrect):
+		return lp.text(msgInvalidCorrectError)
+	case errors.Is(err, deck.ErrInvalidStreak):
+		return lp.text(msgInvalidStreakError)
+	default:
+		return err.Error()
+	}
+}
+
+type LoggingScanner struct {
+	scanner    *bufio.Scanner
+	logBuilder *strings.Builder
+	closed     *bool
+}
+
+func (ls *LoggingScanner) Scan() bool {
+	if ls.scanner.Scan() {
+		return true
+	}
+	*ls.closed = true
+	return false
+}
+
+func (ls *LoggingScanner) Closed() bool {
+	return *ls.closed
+}
+
+func (ls *LoggingScanner) Text() string {
+	text := ls.scanner.Text()
+	ls.logBuilder.WriteString(text + "\n")
+	return text
+}
+
+func isTerminal(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+func parseDelimiter(s string) (rune, error) {
+	if s == `\t` {
+		return '\t', nil
+	}
+	if utf8.RuneCountInString(s) != 1 {
+		return 0, fmt.Errorf("delimiter must be a single character, got %q", s)
+	}
+	delimiter, _ := utf8.DecodeRuneInString(s)
+	if delimiter == '"' || delimiter == '\r' || delimiter == '\n' || delimiter == utf8.RuneError {
+		return 0, fmt.Errorf("%q can't be used as a delimiter", s)
+	}
+	return delimiter, nil
+}
+
+func printSessionSummary(lp LoggingPrinter, stats SessionStats) {
+	lp.Say(msgSessionSummary, stats.Added, stats.Removed, stats.Asked, stats.Correct)
+}
+
+func undo(session *Session, lp LoggingPrinter, fc *deck.Flashcards) {
+	if len(session.undoHistory) == 0 {
+		lp.Say(msgNothingToUndo)
+		return
+	}
+	fc.Restore(session.undoHistory[len(session.undoHistory)-1])
+	session.undoHistory = session.undoHistory[:len(session.undoHistory)-1]
+	session.saveIfAutosave(lp, fc)
+	lp.Say(msgUndone)
+}
+
+func inputChecked(ls LoggingScanner, lp LoggingPrinter, read func() string, check func(string) string) string {
+	s := read()
+	for problem := check(s); problem != "" && !ls.Closed(); problem = check(s) {
+		lp.Println(problem)
+		s = read()
+	}
+	return s
+}
+
+func inputCheckedString(ls LoggingScanner, lp LoggingPrinter, check func(string) string) string {
+	return inputChecked(ls, lp, func() string {
+		ls.Scan()
+		return ls.Text()
+	}, check)
+}
+
+const endOfDefinition = "."
+
+func readDefinition(ls LoggingScanner, multiline bool) string {
+	ls.Scan()
+	if !multiline {
+		return ls.Text()
+	}
+	var lines []string
+	for line := ls.Text(); line != endOfDefinition; line = ls.Text() {
+		lines = append(lines, line)
+		if !ls.Scan() {
+			break
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+func definitionPrompt(lp LoggingPrinter, prompt string, multiline bool) string {
+	if multiline {
+		prompt += lp.text(msgMultilineHint, endOfDefinition)
+	}
+	return prompt + ":"
+}
+
+func inputCheckedDefinition(ls LoggingScanner, lp LoggingPrinter, multiline bool, check func(string) string) string {
+	return inputChecked(ls, lp, func() string {
+		return readDefinition(ls, multiline)
+	}, check)
+}
+
+func addFlashcard(session *Session, ls LoggingScanner, lp LoggingPrinter, fc *deck.Flashcards) {
+	lp.Ask(msgCardPrompt)
+	term := inputCheckedString(ls, lp, func(s string) string {
+		if deck.ValidateTerm(s) != nil {
+			return lp.text(msgEmptyCard)
+		}
+		if _, exists := fc.FindDefinitionByTerm(s); exists {
+			return lp.text(msgCardExists, s)
+		}
+		return ""
+	})
+
+	lp.Prompt(definitionPrompt(lp, lp.text(msgDefinitionPrompt), session.multilineDefinitions))
+	definition := inputCheckedDefinition(ls, lp, session.multilineDefinitions, func(s string) string {
+		if deck.ValidateDefinition(s) != nil {
+			return lp.text(msgEmptyDefinition)
+		}
+		if _, exists := fc.FindTermByDefinition(s); exists {
+			return lp.text(msgDefinitionExists, s)
+		}
+		return ""
+	})
+
+	lp.Ask(msgTagsPrompt)
+	ls.Scan()
+	tags := deck.ParseTags(ls.Text())
+	if ls.Closed() {
+		return
+	}
+
+	newFlashcard := deck.Flashcard{
+		Term:       term,
+		Definition: definition,
+		Mistakes:   0,
+		Tags:       tags,
+	}
+	session.rememberForUndo(fc.Snapshot())
+	fc.CreateOrUpdate(newFlashcard)
+	session.saveIfAutosave(lp, fc)
+	session.stats.Added++
+	lp.Say(msgPairAdded, term, definition)
+}
+
+func batchAddFlashcards(session *Session, ls LoggingScanner, lp LoggingPrinter, fc *deck.Flashcards) {
+	lp.Ask(msgBatchPrompt)
+	snapshot := fc.Snapshot()
+	added, skipped := 0, 0
+	for ls.Scan() {
+		line := ls.Text()
+		if line == "" {
+			break
+		}
+
+		term, definition, found := strings.Cut(line, "=")
+		term, definition = strings.TrimSpace(term), strings.TrimSpace(definition)
+		if !found {
+			lp.Say(msgBatchNoSeparator, line)
+			skipped++
+			continue
+		}
+		if err := deck.Validate(term, definition); err != nil {
+			lp.Say(msgBatchInvalid, line, lp.errorText(err))
+			skipped++
+			continue
+		}
+		if _, exists := fc.FindDefinitionByTerm(term); exists {
+			lp.Say(msgBatchCardExists, term)
+			skipped++
+			continue
+		}
+		if _, exists := fc.FindTermByDefinition(definition); exists {
+			lp.Say(msgBatchDefinitionExists, definition)
+			skipped++
+			continue
+		}
+		fc.CreateOrUpdate(deck.Flashcard{Term: term, Definition: definition})
+		added++
+	}
+
+	if added > 0 {
+		session.rememberForUndo(snapshot)
+		session.saveIfAutosave(lp, fc)
+	}
+	session.stats.Added += added
+	lp.Say(msgBatchAdded, added, skipped)
+}
+
+func copyFlashcard(session *Session, ls LoggingScanner, lp LoggingPrinter, fc *deck.Flashcards) {
+	lp.Ask(msgWhichCard)
+	ls.Scan()
+	term := ls.Text()
+	if _, exists := fc.GetByTerm(term); !exists {
+		lp.Say(msgCantCopy, term)
+		return
+	}
+
+	lp.Ask(msgNewTermPrompt)
+	newTerm := inputCheckedString(ls, lp, func(s string) string {
+		if deck.ValidateTerm(s) != nil {
+			return lp.text(msgEmptyCard)
+		}
+		if _, exists := fc.FindDefinitionByTerm(s); exists {
+			return lp.text(msgCardExists, s)
+		}
+		return ""
+	})
+	if ls.Closed() {
+		return
+	}
+
+	snapshot := fc.Snapshot()
+	if fc.Copy(term, newTerm) {
+		session.rememberForUndo(snapshot)
+		session.saveIfAutosave(lp, fc)
+		session.stats.Added++
+		lp.Say(msgCopied, term, newTerm)
+	}
+}
+
+func deduplicateFlashcards(session *Session, lp LoggingPrinter, fc *deck.Flashcards) {
+	snapshot := fc.Snapshot()
+	removed := fc.Deduplicate()
+	if removed == 0 {
+		lp.Say(msgNoDuplicates)
+		return
+	}
+	session.rememberForUndo(snapshot)
+	session.saveIfAutosave(lp, fc)
+	lp.Say(msgDeduplicated, removed)
+}
+
+func removeFlashcard(session *Session, ls LoggingScanner, lp LoggingPrinter, fc *deck.Flashcards) {
+	lp.Ask(msgWhichCard)
+	ls.Scan()
+	term := session.listedTerm(fc, ls.Text())
+	snapshot := fc.Snapshot()
+	if fc.RemoveByTerm(term) {
+		session.rememberForUndo(snapshot)
+		session.saveIfAutosave(lp, fc)
+		session.stats.Removed++
+		lp.Say(msgRemoved)
+	} else {
+		lp.Say(msgCantRemove, term)
+	}
+}
+
+func editFlashcard(session *Session, ls LoggingScanner, lp LoggingPrinter, fc *deck.Flashcards) {
+	lp.Ask(msgWhichCard)
+	ls.Scan()
+	term := session.listedTerm(fc, ls.Text())
+	flashcard, exists := fc.GetByTerm(term)
+	definition := flashcard.Definition
+	if !exists {
+		lp.Say(msgCantEdit, term)
+		return
+	}
+
+	lp.Ask(msgEditTermPrompt, term)
+	newTerm := inputCheckedString(ls, lp, func(s string) string {
+		if s == "" || s == term {
+			return ""
+		}
+		if deck.ValidateTerm(s) != nil {
+			return lp.text(msgEmptyCard)
+		}
+		if _, exists := fc.FindDefinitionByTerm(s); exists {
+			return lp.text(msgCardExists, s)
+		}
+		return ""
+	})
+	if newTerm == "" {
+		newTerm = term
+	}
+
+	lp.Prompt(definitionPrompt(lp, lp.text(msgEditDefinitionPrompt, definition), session.multilineDefinitions))
+	newDefinition := inputCheckedDefinition(ls, lp, session.multilineDefinitions, func(s string) string {
+		if s == "" || s == definition {
+			return ""
+		}
+		if deck.ValidateDefinition(s) != nil {
+			return lp.text(msgEmptyDefinition)
+		}
+		if _, exists := fc.FindTermByDefinition(s); exists {
+			return lp.text(msgDefinitionExists, s)
+		}
+		return ""
+	})
+	if newDefinition == "" {
+		newDefinition = definition
+	}
+
+	lp.Ask(msgEditTagsPrompt, strings.Join(flashcard.Tags, ","))
+	ls.Scan()
+	newTags := flashcard.Tags
+	switch input := ls.Text(); input {
+	case "":
+	case "-":
+		newTags = nil
+	default:
+		newTags = deck.ParseTags(input)
+	}
+
+	if ls.Closed() {
+		return
+	}
+
+	snapshot := fc.Snapshot()
+	if err := fc.Edit(term, newTerm, newDefinition, newTags); err != nil {
+		lp.Say(msgCantEditError, term, lp.errorText(err))
+		return
+	}
+	session.rememberForUndo(snapshot)
+	session.saveIfAutosave(lp, fc)
+	lp.Say(msgEdited)
+}
+
+func swapFlashcard(session *Session, ls LoggingScanner, lp LoggingPrinter, fc *deck.Flashcards) {
+	lp.Ask(msgWhichCard)
+	ls.Scan()
+	term := ls.Text()
+	snapshot := fc.Snapshot()
+	if err := fc.Swap(term); err != nil {
+		lp.Say(msgCantSwap, term, lp.errorText(err))
+		return
+	}
+	session.rememberForUndo(snapshot)
+	session.saveIfAutosave(lp, fc)
+	newTerm, _ := fc.FindTermByDefinition(term)
+	lp.Say(msgSwapped, newTerm, term)
+}
+
+type askMode int
+
+const (
+	askDefinition askMode = iota
+	askTerm
+	askChoice
+	askMixed
 )
 
-type LoggingPrinter struct {
-	logBuilder *strings.Builder
+type outcome int
+
+const (
+	outcomeWrong outcome = iota
+	outcomeCorrect
+	outcomeStopped
+)
+
+func joinLines(s string) string {
+	lines := strings.Split(strings.ReplaceAll(s, "\r\n", "\n"), "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimSpace(line)
+	}
+	return strings.Join(lines, " ")
+}
+
+func matchAnswer(expected, got string, lenient bool) bool {
+	if strings.Contains(expected, "\n") {
+		expected, got = joinLines(expected), joinLines(got)
+	}
+	if lenient {
+		return strings.EqualFold(strings.TrimSpace(expected), strings.TrimSpace(got))
+	}
+	return expected == got
+}
+
+func levenshtein(a, b string) int {
+	source, target := []rune(a), []rune(b)
+	previous := make([]int, len(target)+1)
+	current := make([]int, len(target)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(source); i++ {
+		current[0] = i
+		for j := 1; j <= len(target); j++ {
+			cost := 1
+			if source[i-1] == target[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+	return previous[len(target)]
+}
+
+func nearAnswer(expected, got string, lenient bool, distance int) bool {
+	if distance <= 0 {
+		return false
+	}
+	if lenient {
+		expected, got = strings.ToLower(strings.TrimSpace(expected)), strings.ToLower(strings.TrimSpace(got))
+	}
+	return levenshtein(expected, got) <= distance
+}
+
+func readAnswer(ls LoggingScanner, lp LoggingPrinter, timeout time.Duration) (string, bool) {
+	if timeout <= 0 {
+		ls.Scan()
+		return ls.Text(), true
+	}
+
+	scanned := make(chan bool, 1)
+	go func() {
+		scanned <- ls.Scan()
+	}()
+	select {
+	case <-scanned:
+		return ls.Text(), true
+	case <-time.After(timeout):
+		lp.Say(msgTimesUp)
+		if <-scanned {
+			ls.Text()
+		}
+		return "", false
+	}
+}
+
+func askMultipleChoice(session *Session, ls LoggingScanner, lp LoggingPrinter, fc *deck.Flashcards, flashcard deck.Flashcard, record bool) outcome {
+	options := fc.Choices(flashcard, 3)
+
+	lp.Say(msgChoosePrompt, flashcard.Term)
+	for i, option := range options {
+		lp.Printf("%d. %s\n", i+1, option.Definition)
+	}
+
+	answer, inTime := readAnswer(ls, lp, session.answerTimeout)
+	choice, err := strconv.Atoi(answer)
+	for inTime && !ls.Closed() && (err != nil || choice < 1 || choice > len(options)) {
+		lp.Say(msgChoiceRange, len(options))
+		answer, inTime = readAnswer(ls, lp, session.answerTimeout)
+		choice, err = strconv.Atoi(answer)
+	}
+	if ls.Closed() {
+		return outcomeWrong
+	}
+
+	if inTime && options[choice-1].Term == flashcard.Term {
+		recordAnswer(fc, flashcard.Term, true, record)
+		lp.SayCorrect(msgCorrect)
+		return outcomeCorrect
+	}
+	recordAnswer(fc, flashcard.Term, false, record)
+	lp.SayWrong(msgWrong, flashcard.Definition)
+	return outcomeWrong
+}
+
+func askFlashcard(session *Session, ls LoggingScanner, lp LoggingPrinter, fc *deck.Flashcards, flashcard deck.Flashcard, mode askMode, record, stoppable bool) outcome {
+	result := checkFlashcard(session, ls, lp, fc, flashcard, mode, record, stoppable)
+	if ls.Closed() || result == outcomeStopped || !record {
+		return result
+	}
+	fc.MarkSeen(flashcard.Term)
+	session.stats.Asked++
+	if result == outcomeCorrect {
+		session.stats.Correct++
+	}
+	session.recordMiss(flashcard.Term, result == outcomeCorrect)
+	return result
+}
+
+const hintRequest = "?"
+
+func recordAnswer(fc *deck.Flashcards, term string, correct, record bool) {
+	switch {
+	case !record:
+	case correct:
+		fc.IncrementCorrect(term)
+	default:
+		fc.IncrementMistakes(term)
+	}
+}
+
+func practiceFlashcards(session *Session, ls LoggingScanner, lp LoggingPrinter, fc *deck.Flashcards) {
+	if fc.Len() == 0 {
+		lp.Say(msgNoCardsToAsk)
+		return
+	}
+	askRandomFlashcards(session, ls, lp, fc, askDefinition, false, fc.GetRandomFc)
+}
+
+func quoteTerms(terms []string) string {
+	quoted := make([]string, len(terms))
+	for i, term := range terms {
+		quoted[i] = strconv.Quote(term)
+	}
+	return strings.Join(quoted, ", ")
+}
+
+func checkFlashcard(session *Session, ls LoggingScanner, lp LoggingPrinter, fc *deck.Flashcards, flashcard deck.Flashcard, mode askMode, record, stoppable bool) outcome {
+	if mode == askMixed {
+		mode = askDefinition
+		if fc.FlipCoin() {
+			mode = askTerm
+		}
+	}
+	if mode == askChoice {
+		return askMultipleChoice(session, ls, lp, fc, flashcard, record)
+	}
+
+	expected := flashcard.Definition
+	if mode == askTerm {
+		expected = flashcard.Term
+		lp.Say(msgAskTermPrompt, flashcard.Definition)
+	} else {
+		lp.Say(msgAskDefinitionPrompt, flashcard.Term)
+	}
+	answer, inTime := readAnswer(ls, lp, session.answerTimeout)
+	if ls.Closed() {
+		return outcomeWrong
+	}
+	if stoppable && answer == stopRequest {
+		return outcomeStopped
+	}
+	hinted := false
+	for mode == askTerm && inTime && answer == hintRequest {
+		hinted = true
+		first, _ := utf8.DecodeRuneInString(expected)
+		lp.Say(msgHint, string(first))
+		answer, inTime = readAnswer(ls, lp, session.answerTimeout)
+	}
+	if !inTime {
+		recordAnswer(fc, flashcard.Term, false, record)
+		lp.SayWrong(msgRightAnswer, expected)
+		return outcomeWrong
+	}
+	if hinted && matchAnswer(expected, answer, session.lenientAnswers) {
+		recordAnswer(fc, flashcard.Term, false, record)
+		lp.SayWrong(msgCorrectWithHint)
+		return outcomeWrong
+	}
+	if matchAnswer(expected, answer, session.lenientAnswers) {
+		recordAnswer(fc, flashcard.Term, true, record)
+		lp.SayCorrect(msgCorrect)
+		return outcomeCorrect
+	}
+
+	if mode == askTerm {
+		definition, exists := fc.FindDefinitionByTerm(answer)
+		if exists && definition == flashcard.Definition && hinted {
+			recordAnswer(fc, flashcard.Term, false, record)
+			lp.SayWrong(msgCorrectWithHint)
+			return outcomeWrong
+		}
+		if exists && definition == flashcard.Definition {
+			recordAnswer(fc, flashcard.Term, true, record)
+			lp.SayCorrect(msgCorrect)
+			return outcomeCorrect
+		}
+		if exists {
+			recordAnswer(fc, flashcard.Term, false, record)
+			lp.SayWrong(msgWrongOtherCard, expected)
+			return outcomeWrong
+		}
+	} else if otherTerms := fc.FindAllTermsByDefinition(answer); len(otherTerms) > 0 {
+		recordAnswer(fc, flashcard.Term, false, record)
+		lp.SayWrong(msgWrongOtherDefinition, expected, quoteTerms(otherTerms))
+		return outcomeWrong
+	}
+	if nearAnswer(expected, answer, session.lenientAnswers, session.fuzzyDistance) {
+		recordAnswer(fc, flashcard.Term, true, record)
+		lp.SayCorrect(msgAlmost, expected)
+		return outcomeCorrect
+	}
+	recordAnswer(fc, flashcard.Term, false, record)
+	lp.SayWrong(msgWrong, expected)
+	return outcomeWrong
+}
+
+func askFlashcards(session *Session, ls LoggingScanner, lp LoggingPrinter, fc *deck.Flashcards, mode askMode) {
+	if fc.Len() == 0 {
+		lp.Say(msgNoCardsToAsk)
+		return
+	}
+	askRandomFlashcards(session, ls, lp, fc, mode, true, fc.GetRandomFc)
+}
+
+func askSmartFlashcards(session *Session, ls LoggingScanner, lp LoggingPrinter, fc *deck.Flashcards) {
+	if fc.Len() == 0 {
+		lp.Say(msgNoCardsToAsk)
+		return
+	}
+	askRandomFlashcards(session, ls, lp, fc, askDefinition, true, fc.GetWeightedRandomFc)
+}
+
+func askTaggedFlashcards(session *Session, ls LoggingScanner, lp LoggingPrinter, fc *deck.Flashcards) {
+	lp.Ask(msgWhichTag)
+	ls.Scan()
+	tag := ls.Text()
+	taggedCards := fc.FilterByTag(tag)
+	if len(taggedCards) == 0 {
+		lp.Say(msgNoTaggedCards, tag)
+		return
+	}
+	askRandomFlashcards(session, ls, lp, fc, askDefinition, true, func() (deck.Flashcard, bool) {
+		return fc.PickRandom(taggedCards)
+	})
+}
+
+const numberAttempts = 3
+
+func readPositiveInt(ls LoggingScanner, lp LoggingPrinter, prompt message) int {
+	lp.Ask(prompt)
+	for attempt := 0; attempt < numberAttempts; attempt++ {
+		if !ls.Scan() {
+			return 0
+		}
+		if n, err := strconv.Atoi(strings.TrimSpace(ls.Text())); err == nil && n > 0 {
+			return n
+		}
+		lp.Say(msgPositive)
+	}
+	lp.Say(msgCancelled)
+	return 0
+}
+
+func askRandomFlashcards(session *Session, ls LoggingScanner, lp LoggingPrinter, fc *deck.Flashcards, mode askMode, record bool, pick func() (deck.Flashcard, bool)) {
+	times := readPositiveInt(ls, lp, msgHowManyTimes)
+
+	scheduler := deck.NewScheduler(fc, pick, session.requeueChance)
+	asked, correct := 0, 0
+	for i := 0; i < times && !ls.Closed(); i++ {
+		flashcard, ok := scheduler.Next()
+		if !ok {
+			break
+		}
+		lp.Ask(msgQuestionNumber, i+1, times)
+		isCorrect := askFlashcard(session, ls, lp, fc, flashcard, mode, record, false) == outcomeCorrect
+		if ls.Closed() {
+			break
+		}
+		scheduler.Record(flashcard.Term, isCorrect)
+		asked++
+		if isCorrect {
+			correct++
+		}
+	}
+	printVerdict(lp, correct, asked, session.passThreshold)
+}
+
+func printVerdict(lp LoggingPrinter, correct, asked, threshold int) {
+	if asked == 0 {
+		return
+	}
+	score := float64(correct) * 100 / float64(asked)
+	if score >= float64(threshold) {
+		lp.SayCorrect(msgPassed, score)
+	} else {
+		lp.SayWrong(msgFailed, score)
+	}
+}
+
+func askAllFlashcards(session *Session, ls LoggingScanner, lp LoggingPrinter, fc *deck.Flashcards) {
+	if fc.Len() == 0 {
+		lp.Say(msgNoCardsToAsk)
+		return
+	}
+
+	flashcards := fc.Shuffle()
+	if session.listOrder == orderStored {
+		flashcards = fc.All()
+	}
+	correct := 0
+	for _, flashcard := range flashcards {
+		if ls.Closed() {
+			return
+		}
+		if askFlashcard(session, ls, lp, fc, flashcard, askDefinition, true, false) == outcomeCorrect {
+			correct++
+		}
+	}
+	lp.Say(msgAllResult, correct, len(flashcards))
+}
+
+const stopRequest = ":stop"
+
+func askUntilStopped(session *Session, ls LoggingScanner, lp LoggingPrinter, fc *deck.Flashcards) {
+	if fc.Len() == 0 {
+		lp.Say(msgNoCardsToAsk)
+		return
+	}
+
+	lp.Ask(msgStopHint, stopRequest)
+	asked, correct := 0, 0
+	for !ls.Closed() {
+		flashcard, ok := fc.GetRandomFc()
+		if !ok {
+			return
+		}
+		result := askFlashcard(session, ls, lp, fc, flashcard, askDefinition, true, true)
+		if result == outcomeStopped || ls.Closed() {
+			break
+		}
+		asked++
+		if result == outcomeCorrect {
+			correct++
+		}
+	}
+	lp.Say(msgAllResult, correct, asked)
+}
+
+func askMissedFlashcards(session *Session, ls LoggingScanner, lp LoggingPrinter, fc *deck.Flashcards) {
+	var flashcards []deck.Flashcard
+	for _, term := range session.missedTerms {
+		if flashcard, exists := fc.GetByTerm(term); exists {
+			flashcards = append(flashcards, flashcard)
+		}
+	}
+	if len(flashcards) == 0 {
+		lp.Say(msgNoMissedCards)
+		return
+	}
+
+	correct := 0
+	for _, flashcard := range flashcards {
+		if ls.Closed() {
+			return
+		}
+		if askFlashcard(session, ls, lp, fc, flashcard, askDefinition, true, false) == outcomeCorrect {
+			correct++
+		}
+	}
+	lp.Say(msgAllResult, correct, len(flashcards))
+}
+
+func askNewFlashcards(session *Session, ls LoggingScanner, lp LoggingPrinter, fc *deck.Flashcards) {
+	flashcards := fc.Unseen()
+	if len(flashcards) == 0 {
+		lp.Say(msgNoNewCards)
+		return
+	}
+
+	correct := 0
+	for _, flashcard := range flashcards {
+		if ls.Closed() {
+			return
+		}
+		if askFlashcard(session, ls, lp, fc, flashcard, askDefinition, true, false) == outcomeCorrect {
+			correct++
+		}
+	}
+	lp.Say(msgAllResult, correct, len(flashcards))
+}
+
+func askStudyPlan(session *Session, ls LoggingScanner, lp LoggingPrinter, fc *deck.Flashcards) {
+	perTag := readPositiveInt(ls, lp, msgPerTagPrompt)
+	if perTag == 0 {
+		return
+	}
+
+	flashcards := fc.StudyPlan(perTag)
+	if len(flashcards) == 0 {
+		lp.Say(msgNoTaggedCardsAtAll)
+		return
+	}
+	correct := 0
+	for _, flashcard := range flashcards {
+		if ls.Closed() {
+			return
+		}
+		if askFlashcard(session, ls, lp, fc, flashcard, askDefinition, true, false) == outcomeCorrect {
+			correct++
+		}
+	}
+	lp.Say(msgAllResult, correct, len(flashcards))
+}
+
+func renameTag(session *Session, ls LoggingScanner, lp LoggingPrinter, fc *deck.Flashcards) {
+	lp.Ask(msgWhichTag)
+	ls.Scan()
+	oldTag := strings.TrimSpace(ls.Text())
+	if len(fc.FilterByTag(oldTag)) == 0 {
+		lp.Say(msgNoTaggedCards, oldTag)
+		return
+	}
+
+	lp.Ask(msgNewTagPrompt)
+	ls.Scan()
+	newTags := deck.ParseTags(ls.Text())
+	for len(newTags) != 1 && !ls.Closed() {
+		lp.Say(msgInvalidTag)
+		ls.Scan()
+		newTags = deck.ParseTags(ls.Text())
+	}
+	if ls.Closed() {
+		return
+	}
+
+	session.rememberForUndo(fc.Snapshot())
+	renamed := fc.RenameTag(oldTag, newTags[0])
+	session.saveIfAutosave(lp, fc)
+	lp.Say(msgTagRenamed, oldTag, newTags[0], renamed)
+}
+
+func removeTag(session *Session, ls LoggingScanner, lp LoggingPrinter, fc *deck.Flashcards) {
+	lp.Ask(msgWhichTag)
+	ls.Scan()
+	tag := strings.TrimSpace(ls.Text())
+	if len(fc.FilterByTag(tag)) == 0 {
+		lp.Say(msgNoTaggedCards, tag)
+		return
+	}
+
+	session.rememberForUndo(fc.Snapshot())
+	removed := fc.RemoveTag(tag)
+	session.saveIfAutosave(lp, fc)
+	lp.Say(msgTagRemoved, tag, removed)
+}
+
+func drillFlashcards(session *Session, ls LoggingScanner, lp LoggingPrinter, fc *deck.Flashcards) {
+	hardestCards := fc.HardestCards()
+	if len(hardestCards) == 0 {
+		lp.Say(msgNoCardsWithErrors)
+		return
+	}
+
+	attempts := 0
+	for _, flashcard := range hardestCards {
+		for !ls.Closed() {
+			attempts++
+			if askFlashcard(session, ls, lp, fc, flashcard, askDefinition, true, false) == outcomeCorrect {
+				break
+			}
+		}
+	}
+	lp.Say(msgDrillResult, len(hardestCards), attempts)
+}
+
+func peekFlashcard(ls LoggingScanner, lp LoggingPrinter, fc *deck.Flashcards) {
+	flashcard, ok := fc.GetRandomFc()
+	if !ok {
+		lp.Say(msgNothingToPeek)
+		return
+	}
+	lp.Say(msgPeekFront, flashcard.Term)
+	ls.Scan()
+	ls.Text()
+	lp.Say(msgPeekBack, flashcard.Term, flashcard.Definition)
+}
+
+func importFlashcards(session *Session, ls LoggingScanner, lp LoggingPrinter, fc *deck.Flashcards) {
+	lp.Ask(msgFileNamePrompt)
+	ls.Scan()
+	filename := ls.Text()
+	if filename == deck.StdStream {
+		lp.Say(msgNoStdinImport)
+		return
+	}
+	snapshot := fc.Snapshot()
+	if _, ok := importFlashcardsFromFile(session, filename, lp, fc); ok {
+		session.rememberForUndo(snapshot)
+	}
+}
+
+func previewImport(ls LoggingScanner, lp LoggingPrinter, fc *deck.Flashcards) {
+	lp.Ask(msgFileNamePrompt)
+	ls.Scan()
+	filename := ls.Text()
+	if filename == deck.StdStream {
+		lp.Say(msgNoStdinImport)
+		return
+	}
+	added, termConflicts, definitionConflicts, err := fc.PreviewImport(filename)
+	if errors.Is(err, os.ErrNotExist) {
+		lp.Say(msgFileNotFound)
+		return
+	}
+	if err != nil {
+		lp.Say(msgReadFailed, err)
+		return
+	}
+	lp.Say(msgImportPreview, added, termConflicts, definitionConflicts)
+}
+
+func importFlashcardsFromFile(session *Session, filename string, lp LoggingPrinter, fc *deck.Flashcards) (int, bool) {
+	result, err := fc.Import(filename, session.mergeOnImport)
+	if errors.Is(err, os.ErrNotExist) {
+		lp.Say(msgFileNotFound)
+		return 0, false
+	}
+	if err != nil {
+		lp.Say(msgReadFailed, err)
+		return 0, false
+	}
+
+	printImportResult(session, lp, result)
+	return result.Added + result.Merged, true
+}
+
+func printImportResult(session *Session, lp LoggingPrinter, result deck.ImportResult) {
+	for _, skipped := range result.Skipped {
+		lp.Say(msgSkipped, skipped.Record, lp.errorText(skipped.Err))
+	}
+	for _, term := range result.Clamped {
+		lp.Say(msgClamped, term)
+	}
+	if result.OverLimit > 0 {
+		lp.Say(msgOverLimit, session.maxCards, result.OverLimit)
+	}
+	if session.mergeOnImport {
+		lp.Say(msgMerged, result.Added, result.Merged)
+	} else {
+		lp.Say(msgLoaded, result.Added)
+	}
+}
+
+var delimiterEscapes = strings.NewReplacer(`\t`, "\t", `\n`, "\n", `\r`, "\r")
+
+func inputDelimiter(ls LoggingScanner, lp LoggingPrinter, prompt message, defaultDelimiter string) string {
+	lp.Ask(prompt, strconv.Quote(defaultDelimiter))
+	ls.Scan()
+	if input := ls.Text(); input != "" {
+		return delimiterEscapes.Replace(input)
+	}
+	return defaultDelimiter
+}
+
+func importQuizlet(session *Session, ls LoggingScanner, lp LoggingPrinter, fc *deck.Flashcards) {
+	lp.Ask(msgFileNamePrompt)
+	ls.Scan()
+	filename := ls.Text()
+	if filename == deck.StdStream {
+		lp.Say(msgNoStdinImport)
+		return
+	}
+	termDelimiter := inputDelimiter(ls, lp, msgTermDelimiterPrompt, "\t")
+	rowDelimiter := inputDelimiter(ls, lp, msgRowDelimiterPrompt, "\n")
+	if ls.Closed() {
+		return
+	}
+
+	snapshot := fc.Snapshot()
+	result, err := fc.ImportQuizlet(filename, termDelimiter, rowDelimiter, session.mergeOnImport)
+	if errors.Is(err, os.ErrNotExist) {
+		lp.Say(msgFileNotFound)
+		return
+	}
+	if err != nil {
+		lp.Say(msgReadFailed, err)
+		return
+	}
+	session.rememberForUndo(snapshot)
+	printImportResult(session, lp, result)
 }
 
-func (lp *LoggingPrinter) Printf(format string, a ...any) {
-	line := fmt.Sprintf(format, a...)
-	lp.logBuilder.Write([]byte(line))
-	fmt.Print(line)
-}
+func mergeFiles(session *Session, ls LoggingScanner, lp LoggingPrinter, fc *deck.Flashcards) {
+	lp.Ask(msgFileNamesPrompt)
+	ls.Scan()
+	filenames := strings.Fields(ls.Text())
+	if len(filenames) == 0 {
+		return
+	}
 
-func (lp *LoggingPrinter) Println(a ...any) {
-	line := fmt.Sprintln(a...)
-	lp.logBuilder.Write([]byte(line))
-	fmt.Print(line)
+	snapshot := fc.Snapshot()
+	total, imported := 0, false
+	for _, filename := range filenames {
+		lp.Say(msgFileLabel, filename)
+		if filename == deck.StdStream {
+			lp.Say(msgNoStdinImport)
+			continue
+		}
+		if loaded, ok := importFlashcardsFromFile(session, filename, lp, fc); ok {
+			total += loaded
+			imported = true
+		}
+	}
+	if imported {
+		session.rememberForUndo(snapshot)
+	}
+	lp.Say(msgMergeTotal, total, len(filenames))
 }
 
-type LoggingScanner struct {
-	scanner    *bufio.Scanner
-	logBuilder *strings.Builder
+func confirm(ls LoggingScanner, lp LoggingPrinter, prompt message, assumeYes bool) bool {
+	if assumeYes {
+		return true
+	}
+	lp.Ask(prompt)
+	ls.Scan()
+	if answer := ls.Text(); answer == "y" || answer == "Y" {
+		return true
+	}
+	lp.Say(msgCancelled)
+	return false
 }
 
-func (ls *LoggingScanner) Scan() bool {
-	return ls.scanner.Scan()
+func inputExportFilename(session *Session, ls LoggingScanner, lp LoggingPrinter) (string, bool) {
+	lp.Ask(msgFileNamePrompt)
+	ls.Scan()
+	filename := ls.Text()
+	if _, err := os.Stat(filename); err == nil {
+		return filename, confirm(ls, lp, msgOverwritePrompt, session.assumeYes)
+	}
+	return filename, true
 }
 
-func (ls *LoggingScanner) Text() string {
-	text := ls.scanner.Text()
-	ls.logBuilder.WriteString(text + "\n")
-	return text
+func exportFlashcards(session *Session, ls LoggingScanner, lp LoggingPrinter, fc *deck.Flashcards) {
+	if filename, ok := inputExportFilename(session, ls, lp); ok {
+		exportFlashcardsToFile(session, filename, lp, fc)
+	}
 }
 
-type Flashcard struct {
-	Term       string
-	Definition string
-	Mistakes   int
+func exportFlashcardsToFile(session *Session, filename string, lp LoggingPrinter, fc *deck.Flashcards) bool {
+	savedAmount, err := fc.WriteFile(filename)
+	if err != nil {
+		lp.Say(msgWriteFailed, err)
+		return false
+	}
+	if filename != deck.StdStream {
+		session.lastExportPath = filename
+	}
+	lp.Say(msgSaved, savedAmount)
+	return true
 }
 
-type Flashcards struct {
-	elements map[int]Flashcard
+func saveFlashcards(session *Session, lp LoggingPrinter, fc *deck.Flashcards) {
+	if session.lastExportPath == "" {
+		lp.Say(msgNoSavePath)
+		return
+	}
+	exportFlashcardsToFile(session, session.lastExportPath, lp, fc)
 }
 
-func (fc *Flashcards) FindDefinitionByTerm(term string) (string, bool) {
-	for _, flashcard := range fc.elements {
-		if flashcard.Term == term {
-			return flashcard.Definition, true
-		}
+func exportAnkiFlashcards(session *Session, ls LoggingScanner, lp LoggingPrinter, fc *deck.Flashcards) {
+	filename, ok := inputExportFilename(session, ls, lp)
+	if !ok {
+		return
+	}
+	savedAmount, err := fc.WriteAnkiTSV(filename)
+	if err != nil {
+		lp.Say(msgWriteFailed, err)
+		return
 	}
-	return "", false
+	lp.Say(msgSaved, savedAmount)
 }
 
-func (fc *Flashcards) FindTermByDefinition(definition string) (string, bool) {
-	for _, flashcard := range fc.elements {
-		if flashcard.Definition == definition {
-			return flashcard.Term, true
-		}
+func exportMarkdownFlashcards(session *Session, ls LoggingScanner, lp LoggingPrinter, fc *deck.Flashcards) {
+	filename, ok := inputExportFilename(session, ls, lp)
+	if !ok {
+		return
+	}
+	savedAmount, err := fc.WriteMarkdown(filename)
+	if err != nil {
+		lp.Say(msgWriteFailed, err)
+		return
 	}
-	return "", false
+	lp.Say(msgSaved, savedAmount)
 }
 
-func (fc *Flashcards) CreateOrUpdate(flashcard Flashcard) {
-	for index, existingFlashcard := range fc.elements {
-		if existingFlashcard.Term == flashcard.Term {
-			fc.elements[index] = flashcard
-			return
-		}
+func exportReversedFlashcards(session *Session, ls LoggingScanner, lp LoggingPrinter, fc *deck.Flashcards) {
+	filename, ok := inputExportFilename(session, ls, lp)
+	if !ok {
+		return
+	}
+	savedAmount, err := fc.WriteReversedCSV(filename)
+	if err != nil {
+		lp.Say(msgWriteFailed, err)
+		return
 	}
-	fc.elements[len(fc.elements)] = flashcard
+	lp.Say(msgSaved, savedAmount)
 }
 
-func (fc *Flashcards) RemoveByTerm(term string) {
-	lastIndex := len(fc.elements) - 1
-	for index, flashcard := range fc.elements {
-		if flashcard.Term == term {
-			fc.elements[index] = fc.elements[lastIndex]
-			delete(fc.elements, lastIndex)
-		}
+func splitFlashcards(ls LoggingScanner, lp LoggingPrinter, fc *deck.Flashcards) {
+	if fc.Len() == 0 {
+		lp.Say(msgDeckEmpty)
+		return
+	}
+	lp.Ask(msgFileNamePrompt)
+	ls.Scan()
+	base := ls.Text()
+	parts := readPositiveInt(ls, lp, msgPartsPrompt)
+	if parts == 0 {
+		return
+	}
+	filenames, err := fc.SplitCSV(base, parts)
+	if err != nil {
+		lp.Say(msgWriteFailed, err)
+		return
 	}
+	lp.Say(msgSplitSaved, len(filenames), strings.Join(filenames, ", "))
 }
 
-func (fc *Flashcards) GetRandomFc() Flashcard {
-	return fc.elements[rand.Intn(len(fc.elements))]
+func exportStats(session *Session, ls LoggingScanner, lp LoggingPrinter, fc *deck.Flashcards) {
+	filename, ok := inputExportFilename(session, ls, lp)
+	if !ok {
+		return
+	}
+	if err := fc.WriteStatsJSON(filename); err != nil {
+		lp.Say(msgWriteFailed, err)
+		return
+	}
+	lp.Say(msgStatsSaved)
 }
 
-func (fc *Flashcards) WriteCSV(filename string) int {
-	file, err := os.Create(filename)
+func exportHardestFlashcards(session *Session, ls LoggingScanner, lp LoggingPrinter, fc *deck.Flashcards) {
+	if len(fc.HardestCards()) == 0 {
+		lp.Say(msgNoCardsWithErrors)
+		return
+	}
+	filename, ok := inputExportFilename(session, ls, lp)
+	if !ok {
+		return
+	}
+	savedAmount, err := fc.WriteHardestCSV(filename)
 	if err != nil {
-		log.Fatal(err)
+		lp.Say(msgWriteFailed, err)
+		return
 	}
-	defer func(file *os.File) {
-		err := file.Close()
-		if err != nil {
-			log.Fatal(err)
+	lp.Say(msgSaved, savedAmount)
+}
+
+func checkHardestCards(lp LoggingPrinter, fc *deck.Flashcards) {
+	hardestCards := fc.HardestCards()
+	switch len(hardestCards) {
+	case 0:
+		lp.Say(msgNoCardsWithErrors)
+	case 1:
+		lp.write(lp.text(msgHardestCard, hardestCards[0].Term, hardestCards[0].Mistakes))
+	default:
+		sb := strings.Builder{}
+		for i, hardCard := range hardestCards {
+			sb.Write([]byte("\""))
+			sb.Write([]byte(hardCard.Term))
+			sb.Write([]byte("\""))
+			if i != len(hardestCards)-1 {
+				sb.Write([]byte(", "))
+			}
 		}
-	}(file)
+		lp.write(lp.text(msgHardestCards, sb.String(), hardestCards[0].Mistakes))
+	}
+}
 
-	writer := csv.NewWriter(file)
-	defer writer.Flush()
+func printTopHardest(ls LoggingScanner, lp LoggingPrinter, fc *deck.Flashcards) {
+	n := readPositiveInt(ls, lp, msgHowManyCards)
+	if n == 0 {
+		return
+	}
 
-	for _, flashcard := range fc.elements {
-		record := []string{flashcard.Term, flashcard.Definition, strconv.Itoa(flashcard.Mistakes)}
-		if err := writer.Write(record); err != nil {
-			log.Fatal(err)
-		}
+	hardestCards := fc.TopHardest(n)
+	if len(hardestCards) == 0 {
+		lp.Say(msgNoCardsWithErrors)
+		return
+	}
+	for _, flashcard := range hardestCards {
+		lp.Say(msgHardestEntry, flashcard.Term, flashcard.Mistakes)
 	}
-	return len(fc.elements)
 }
 
-func (fc *Flashcards) ReadCSV(filename string) (int, error) {
-	file, err := os.Open(filename)
-	if err != nil {
-		return 0, err
+func listTroublesomeCards(ls LoggingScanner, lp LoggingPrinter, fc *deck.Flashcards) {
+	lp.Ask(msgMinMistakesPrompt)
+	ls.Scan()
+	n, err := strconv.Atoi(strings.TrimSpace(ls.Text()))
+	for err != nil && !ls.Closed() {
+		lp.Say(msgNotANumber)
+		ls.Scan()
+		n, err = strconv.Atoi(strings.TrimSpace(ls.Text()))
+	}
+	if ls.Closed() {
+		return
 	}
-	defer func(file *os.File) {
-		err := file.Close()
-		if err != nil {
-			log.Fatal(err)
-		}
-	}(file)
 
-	reader := csv.NewReader(file)
-	records, err := reader.ReadAll()
-	if err != nil {
-		log.Fatal(err)
+	flashcards := fc.CardsWithAtLeast(n)
+	if len(flashcards) == 0 {
+		lp.Say(msgNoTroublesomeCards, n)
+		return
+	}
+	for _, flashcard := range flashcards {
+		lp.Say(msgHardestEntry, flashcard.Term, flashcard.Mistakes)
+	}
+}
+
+func listStaleCards(ls LoggingScanner, lp LoggingPrinter, fc *deck.Flashcards) {
+	days := readPositiveInt(ls, lp, msgStaleDaysPrompt)
+	if days == 0 {
+		return
 	}
 
-	for _, record := range records {
-		mistakes, _ := strconv.Atoi(record[2])
-		loadedFlashcard := Flashcard{
-			Term:       record[0],
-			Definition: record[1],
-			Mistakes:   mistakes,
+	flashcards := fc.StaleCards(time.Duration(days) * 24 * time.Hour)
+	if len(flashcards) == 0 {
+		lp.Say(msgNoStaleCards, days)
+		return
+	}
+	for _, flashcard := range flashcards {
+		if flashcard.LastSeen.IsZero() {
+			lp.Say(msgStaleNever, flashcard.Term)
+		} else {
+			lp.Say(msgStaleEntry, flashcard.Term, flashcard.LastSeen.Local().Format("2006-01-02 15:04"))
 		}
-		fc.CreateOrUpdate(loadedFlashcard)
 	}
+}
 
-	return len(records), nil
+func listMasteredCards(lp LoggingPrinter, fc *deck.Flashcards) {
+	easiestCards := fc.EasiestCards()
+	if len(easiestCards) == 0 {
+		lp.Say(msgNoMasteredCards)
+		return
+	}
+	for _, flashcard := range easiestCards {
+		lp.Say(msgMasteredEntry, flashcard.Term, flashcard.Definition, flashcard.Correct)
+	}
 }
 
-func (fc *Flashcards) ResetStats() {
-	for i, flashcard := range fc.elements {
-		flashcard.Mistakes = 0
-		fc.elements[i] = flashcard
+func lookupTrimmed(find func(string) (string, bool), input string) (string, bool) {
+	if found, exists := find(input); exists {
+		return found, true
 	}
+	return find(strings.TrimSpace(input))
 }
 
-func (fc *Flashcards) HardestCards() []Flashcard {
-	maxMistakes := 0
-	for _, flashcard := range fc.elements {
-		maxMistakes = max(maxMistakes, flashcard.Mistakes)
+func defineFlashcard(ls LoggingScanner, lp LoggingPrinter, fc *deck.Flashcards) {
+	lp.Ask(msgWhichCard)
+	ls.Scan()
+	term := ls.Text()
+	definition, exists := lookupTrimmed(fc.FindDefinitionByTerm, term)
+	if !exists {
+		lp.Say(msgNoSuchCard, term)
+		return
 	}
+	lp.Say(msgDefinitionIs, strings.TrimSpace(term), definition)
+}
 
-	var hardestCards []Flashcard
+func termOfFlashcard(ls LoggingScanner, lp LoggingPrinter, fc *deck.Flashcards) {
+	lp.Ask(msgWhichDefinition)
+	ls.Scan()
+	definition := ls.Text()
+	term, exists := lookupTrimmed(fc.FindTermByDefinition, definition)
+	if !exists {
+		lp.Say(msgNoSuchDefinition, definition)
+		return
+	}
+	lp.Say(msgTermIs, strings.TrimSpace(definition), term)
+}
 
-	if maxMistakes == 0 {
-		return hardestCards
+func printCardInfo(session *Session, ls LoggingScanner, lp LoggingPrinter, fc *deck.Flashcards) {
+	lp.Ask(msgWhichCard)
+	ls.Scan()
+	term := session.listedTerm(fc, ls.Text())
+	flashcard, exists := fc.GetByTerm(term)
+	if !exists {
+		lp.Say(msgNoSuchCard, term)
+		return
 	}
 
-	for _, flashcard := range fc.elements {
-		if flashcard.Mistakes == maxMistakes {
-			hardestCards = append(hardestCards, flashcard)
-		}
+	lp.Println(lp.card(flashcard))
+	lp.Say(msgInfoCorrect, flashcard.Correct)
+	lp.Say(msgInfoStreak, flashcard.Streak)
+	if len(flashcard.Tags) == 0 {
+		lp.Say(msgInfoNoTags)
+	} else {
+		lp.Say(msgInfoTags, strings.Join(flashcard.Tags, ", "))
 	}
+	if flashcard.LastSeen.IsZero() {
+		lp.Say(msgInfoNeverSeen)
+	} else {
+		lp.Say(msgInfoLastSeen, flashcard.LastSeen.Local().Format("2006-01-02 15:04"))
+	}
+}
+
+const (
+	orderByTerm = "term"
+	orderStored = "stored"
+)
 
-	return hardestCards
+func shuffleFlashcards(session *Session, lp LoggingPrinter, fc *deck.Flashcards) {
+	if fc.Len() == 0 {
+		lp.Say(msgDeckEmpty)
+		return
+	}
+	session.rememberForUndo(fc.Snapshot())
+	fc.ShuffleOrder()
+	session.saveIfAutosave(lp, fc)
+	lp.Say(msgShuffled)
+	if session.listOrder != orderStored {
+		lp.Say(msgShuffleOrderNote, orderStored)
+	}
 }
 
-func (fc *Flashcards) IncrementMistakes(term string) {
-	for i, flashcard := range fc.elements {
-		if flashcard.Term == term {
-			flashcard.Mistakes += 1
-			fc.elements[i] = flashcard
-		}
+func listFlashcards(session *Session, lp LoggingPrinter, fc *deck.Flashcards) {
+	if fc.Len() == 0 {
+		lp.Say(msgDeckEmpty)
+		return
+	}
+	flashcards := fc.SortedByTerm()
+	if session.listOrder == orderStored {
+		flashcards = fc.All()
+	}
+	session.listedTerms = session.listedTerms[:0]
+	for i, flashcard := range flashcards {
+		session.listedTerms = append(session.listedTerms, flashcard.Term)
+		lp.Say(msgListEntry, i+1, lp.card(flashcard), flashcard.Streak)
 	}
 }
 
-func inputUniqueString(ls LoggingScanner, lp LoggingPrinter, checkExists func(string) bool, msgTmp string) string {
-	ls.Scan()
-	s := ls.Text()
-	for exists := checkExists(s); exists; exists = checkExists(s) {
-		lp.Printf(msgTmp, s)
-		ls.Scan()
-		s = ls.Text()
+func printCardStats(lp LoggingPrinter, fc *deck.Flashcards) {
+	if fc.Len() == 0 {
+		lp.Say(msgDeckEmpty)
+		return
+	}
+	for _, flashcard := range fc.SortedByTerm() {
+		if flashcard.Correct+flashcard.Mistakes == 0 {
+			lp.Say(msgNeverAsked, flashcard.Term)
+			continue
+		}
+		lp.Say(msgCardStats,
+			flashcard.Term, flashcard.Correct, flashcard.Mistakes, flashcard.Accuracy()*100)
 	}
-	return s
 }
 
-func addFlashcard(ls LoggingScanner, lp LoggingPrinter, fc Flashcards) {
-	lp.Println("The card:")
-	term := inputUniqueString(ls, lp, func(s string) bool {
-		_, exists := fc.FindDefinitionByTerm(s)
-		return exists
-	}, "The card \"%s\" already exists. Try again:\n")
+func recommendTag(lp LoggingPrinter, fc *deck.Flashcards) {
+	tag, accuracy := fc.WeakestTag()
+	if tag == "" {
+		lp.Say(msgNothingToRecommend)
+		return
+	}
+	lp.Say(msgRecommendation, tag, accuracy*100)
+}
 
-	lp.Println("The definition of the card:")
-	definition := inputUniqueString(ls, lp, func(s string) bool {
-		_, exists := fc.FindTermByDefinition(s)
-		return exists
-	}, "The definition \"%s\" already exists. Try again:\n")
+func searchFlashcards(ls LoggingScanner, lp LoggingPrinter, fc *deck.Flashcards) {
+	lp.Ask(msgSearchPrompt)
+	ls.Scan()
+	matches := fc.Search(ls.Text())
+	if len(matches) == 0 {
+		lp.Say(msgNoMatches)
+		return
+	}
+	for _, flashcard := range matches {
+		lp.Println(lp.card(flashcard))
+	}
+}
 
-	newFlashcard := Flashcard{
-		Term:       term,
-		Definition: definition,
-		Mistakes:   0,
+func countFlashcards(lp LoggingPrinter, fc *deck.Flashcards) {
+	stats := fc.Stats()
+	if stats.Cards == 0 {
+		lp.Say(msgDeckEmpty)
+		return
 	}
-	fc.CreateOrUpdate(newFlashcard)
-	lp.Printf("The pair (\"%s\":\"%s\") has been added.\n", term, definition)
+	lp.Say(msgStatsCards, stats.Cards)
+	lp.Say(msgStatsCardsWithMistakes, stats.CardsWithMistakes)
+	lp.Say(msgStatsTotalMistakes, stats.TotalMistakes)
+	lp.Say(msgStatsAverageMistakes, stats.AverageMistakes)
 }
 
-func removeFlashcard(ls LoggingScanner, lp LoggingPrinter, fc Flashcards) {
-	lp.Println("Which card?")
+func setMistakes(session *Session, ls LoggingScanner, lp LoggingPrinter, fc *deck.Flashcards) {
+	lp.Ask(msgWhichCard)
 	ls.Scan()
 	term := ls.Text()
-	if _, exists := fc.FindDefinitionByTerm(term); exists {
-		fc.RemoveByTerm(term)
-		lp.Println("The card has been removed.")
-	} else {
-		lp.Printf("Can't remove \"%s\": there is no such card.\n", term)
+	if _, exists := fc.FindDefinitionByTerm(term); !exists {
+		lp.Say(msgCantSetMistakes, term)
+		return
 	}
-}
 
-func askFlashcards(ls LoggingScanner, lp LoggingPrinter, fc Flashcards) {
-	lp.Println("How many times to ask?")
+	lp.Ask(msgMistakesPrompt)
 	ls.Scan()
-	times, err := strconv.Atoi(ls.Text())
-	if err != nil {
-		log.Fatal(err)
+	mistakes, err := strconv.Atoi(ls.Text())
+	for (err != nil || mistakes < 0) && !ls.Closed() {
+		lp.Say(msgNonNegative)
+		ls.Scan()
+		mistakes, err = strconv.Atoi(ls.Text())
+	}
+	if ls.Closed() {
+		return
 	}
 
-	for i := 0; i < times; i++ {
-		flashcard := fc.GetRandomFc()
-		lp.Printf("Print the definition of \"%s\":\n", flashcard.Term)
-		ls.Scan()
-		inputDefinition := ls.Text()
-		if flashcard.Definition == inputDefinition {
-			lp.Println("Correct!")
-			continue
-		}
-		if otherTerm, exists := fc.FindTermByDefinition(inputDefinition); exists {
-			fc.IncrementMistakes(flashcard.Term)
-			lp.Printf("Wrong. The right answer is \"%s\", but your definition is correct for \"%s\"\n", flashcard.Definition, otherTerm)
-			continue
-		}
-		fc.IncrementMistakes(flashcard.Term)
-		lp.Printf("Wrong. The right answer is \"%s\".\n", flashcard.Definition)
+	session.rememberForUndo(fc.Snapshot())
+	fc.SetMistakes(term, mistakes)
+	session.saveIfAutosave(lp, fc)
+	lp.Say(msgMistakesSet, term, mistakes)
+}
+
+func clearFlashcards(session *Session, ls LoggingScanner, lp LoggingPrinter, fc *deck.Flashcards) {
+	if !confirm(ls, lp, msgClearPrompt, session.assumeYes) {
+		return
 	}
+	session.rememberForUndo(fc.Snapshot())
+	removed := fc.Clear()
+	session.stats.Removed += removed
+	session.saveIfAutosave(lp, fc)
+	lp.Say(msgDeckCleared, removed)
 }
 
-func importFlashcards(ls LoggingScanner, lp LoggingPrinter, fc Flashcards) {
-	lp.Println("File name:")
+func resetCard(session *Session, ls LoggingScanner, lp LoggingPrinter, fc *deck.Flashcards) {
+	lp.Ask(msgWhichCard)
 	ls.Scan()
-	filename := ls.Text()
-	importFlashcardsFromFile(filename, lp, fc)
+	term := session.listedTerm(fc, ls.Text())
+	snapshot := fc.Snapshot()
+	if !fc.ResetCard(term) {
+		lp.Say(msgNoSuchCard, term)
+		return
+	}
+	session.rememberForUndo(snapshot)
+	session.saveIfAutosave(lp, fc)
+	lp.Say(msgCardReset, term)
 }
 
-func importFlashcardsFromFile(filename string, lp LoggingPrinter, fc Flashcards) {
-	loadedAmount, err := fc.ReadCSV(filename)
-	if err != nil {
-		lp.Println("File not found.")
+func resetTag(session *Session, ls LoggingScanner, lp LoggingPrinter, fc *deck.Flashcards) {
+	lp.Ask(msgWhichTag)
+	ls.Scan()
+	tag := strings.TrimSpace(ls.Text())
+	snapshot := fc.Snapshot()
+	reset := fc.ResetTag(tag)
+	if reset == 0 {
+		lp.Say(msgNoTaggedCards, tag)
 		return
 	}
-	lp.Printf("%d cards have been loaded.\n", loadedAmount)
+	session.rememberForUndo(snapshot)
+	session.saveIfAutosave(lp, fc)
+	lp.Say(msgTagReset, reset, tag)
 }
 
-func exportFlashcards(ls LoggingScanner, lp LoggingPrinter, fc Flashcards) {
-	lp.Println("File name:")
-	ls.Scan()
-	filename := ls.Text()
-	savedAmount := fc.WriteCSV(filename)
-	lp.Printf("%d cards have been saved.\n", savedAmount)
+func resetStats(session *Session, ls LoggingScanner, lp LoggingPrinter, fc *deck.Flashcards) {
+	if !confirm(ls, lp, msgResetPrompt, session.assumeYes) {
+		return
+	}
+	session.rememberForUndo(fc.Snapshot())
+	fc.ResetStats()
+	session.saveIfAutosave(lp, fc)
+	lp.Say(msgStatsReset)
 }
 
-func checkHardestCards(lp LoggingPrinter, fc Flashcards) {
-	hardestCards := fc.HardestCards()
-	switch len(hardestCards) {
-	case 0:
-		lp.Println("There are no cards with errors.")
-	case 1:
-		lp.Printf("The hardest card is \"%s\". You have %d errors answering it.", hardestCards[0].Term, hardestCards[0].Mistakes)
-	default:
-		sb := strings.Builder{}
-		for i, hardCard := range hardestCards {
-			sb.Write([]byte("\""))
-			sb.Write([]byte(hardCard.Term))
-			sb.Write([]byte("\""))
-			if i != len(hardestCards)-1 {
-				sb.Write([]byte(", "))
-			}
-		}
-		lp.Printf("The hardest cards are %s. You have %d errors answering them.", sb.String(), hardestCards[0].Mistakes)
+func printProgress(session *Session, lp LoggingPrinter, fc *deck.Flashcards) {
+	improvements := fc.Improvements(session.baseline)
+	if len(improvements) == 0 {
+		lp.Say(msgNoProgress)
+		return
+	}
+	for _, improvement := range improvements {
+		lp.Say(msgImprovement, improvement.Term, improvement.Before*100, improvement.After*100)
 	}
 }
 
-func resetStats(lp LoggingPrinter, fc Flashcards) {
-	fc.ResetStats()
-	lp.Println("Card statistics have been reset.")
+func rotateLog(filename string, maxSize int64) error {
+	if maxSize <= 0 {
+		return nil
+	}
+	info, err := os.Stat(filename)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if info.Size() < maxSize {
+		return nil
+	}
+	stamped := filename + "." + time.Now().Format("20060102-150405")
+	rotated := stamped
+	for i := 1; ; i++ {
+		if _, err := os.Stat(rotated); errors.Is(err, os.ErrNotExist) {
+			break
+		}
+		rotated = fmt.Sprintf("%s-%d", stamped, i)
+	}
+	return os.Rename(filename, rotated)
 }
 
-func dumpLogs(ls LoggingScanner, lp LoggingPrinter, logBuilder *strings.Builder) {
-	lp.Println("File name:")
+func dumpLogs(session *Session, ls LoggingScanner, lp LoggingPrinter, logBuilder *strings.Builder) {
+	lp.Ask(msgFileNamePrompt)
 	ls.Scan()
 	filename := ls.Text()
+	if filename == deck.StdStream {
+		lp.print(logBuilder.String())
+		return
+	}
+	if err := rotateLog(filename, session.logMaxSize); err != nil {
+		lp.Say(msgWriteFailed, err)
+		return
+	}
 	file, err := os.OpenFile(filename, os.O_APPEND|os.O_WRONLY|os.O_CREATE, 0644)
 	if err != nil {
-		log.Fatal(err)
+		lp.Say(msgWriteFailed, err)
+		return
 	}
-	successLog := "The log has been saved."
-	lp.Println(successLog)
+	lp.Say(msgLogSaved)
 	_, err = file.WriteString(logBuilder.String())
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
 	if err != nil {
-		log.Fatal(err)
+		lp.Say(msgWriteFailed, err)
 	}
 }
 
+const (
+	exitOK           = 0
+	exitUsage        = 1
+	exitImportFailed = 2
+	exitExportFailed = 3
+	exitServeFailed  = 4
+)
+
+func printVersion(w io.Writer) {
+	fmt.Fprintln(w, "flashcards", version)
+}
+
 func main() {
-	flashcards := Flashcards{elements: make(map[int]Flashcard)}
-	scanner := bufio.NewScanner(os.Stdin)
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	session := &Session{}
+	flashcards := deck.NewFlashcards()
+	flashcards.SetStdio(stdin, stdout)
+	scanner := bufio.NewScanner(stdin)
 	logBuilder := &strings.Builder{}
-	ls := LoggingScanner{scanner: scanner, logBuilder: logBuilder}
-	lp := LoggingPrinter{logBuilder: logBuilder}
+	ls := LoggingScanner{scanner: scanner, logBuilder: logBuilder, closed: new(bool)}
+	lp := LoggingPrinter{logBuilder: logBuilder, out: stdout}
+
+	flags := flag.NewFlagSet("flashcards", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	var importFilename, exportFilename, delimiter, serveAddr, language, scriptFilename string
+	var seed int64
+	var csvHeader, csvComments, showVersion bool
+	var timedSeconds int
+	flags.BoolVar(&showVersion, "version", false, "print the version and exit")
+	flags.StringVar(&importFilename, "import_from", "", "file to import from, - for the standard input")
+	flags.StringVar(&exportFilename, "export_to", "", "file to export to, - for the standard output")
+	flags.StringVar(&session.missedFilename, "missed", "", "file keeping the cards answered wrong, to ask them again with ask missed next time")
+	flags.StringVar(&session.autosaveFilename, "autosave", "", "file to load on start and keep saved after every change")
+	flags.StringVar(&delimiter, "delimiter", ",", "CSV field delimiter")
+	flags.BoolVar(&csvComments, "comments", false, "skip lines starting with # in imported CSV files")
+	flags.BoolVar(&csvHeader, "header", false, "write a header row with the column names to CSV files")
+	flags.StringVar(&scriptFilename, "script", "", "file with the actions and their inputs to run instead of the interactive mode")
+	flags.StringVar(&serveAddr, "serve", "", "address to serve the deck over HTTP instead of the interactive mode")
+	flags.IntVar(&timedSeconds, "timed", 0, "seconds to answer each question, 0 for no limit")
+	flags.IntVar(&session.maxCards, "max-cards", 0, "stop importing a file after this many cards, 0 for no limit")
+	flags.BoolVar(&session.mergeOnImport, "merge", false, "on import, add up mistakes of existing cards instead of replacing them")
+	flags.BoolVar(&session.multilineDefinitions, "multiline", false, "read definitions until a line containing only \".\"")
+	flags.IntVar(&session.fuzzyDistance, "fuzzy", 0, "accept answers with up to this many typos, 0 for exact answers only")
+	flags.IntVar(&session.passThreshold, "pass-threshold", 70, "percentage of correct answers needed to pass a quiz")
+	flags.Float64Var(&session.requeueChance, "requeue", 0, "chance from 0 to 1 to ask again a card missed earlier in the same quiz")
+	flags.BoolVar(&session.lenientAnswers, "lenient", false, "ignore case and surrounding spaces when checking answers")
+	flags.StringVar(&language, "lang", defaultLanguage, "language of the messages (en, ru)")
+	flags.StringVar(&session.listOrder, "order", orderByTerm, "order of list: term, or stored (as added, or as left by shuffle), which ask all then follows instead of shuffling")
+	flags.BoolVar(&lp.color, "color", isTerminal(stdout), "color the answer feedback, on by default when printing to a terminal")
+	flags.BoolVar(&lp.quiet, "quiet", false, "don't print the menu and input prompts, for scripted input")
+	flags.Int64Var(&session.logMaxSize, "log-max-size", 0, "bytes after which the log file is renamed with a timestamp and started afresh, 0 to always append")
+	flags.Int64Var(&seed, "seed", 0, "seed for the random card order, to replay a session")
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK
+		}
+		return exitUsage
+	}
+
+	if showVersion {
+		printVersion(stdout)
+		return exitOK
+	}
+
+	var ok bool
+	if lp.messages, ok = messages[language]; !ok {
+		fmt.Fprintf(stderr, "unknown language %q\n", language)
+		return exitUsage
+	}
+	if session.listOrder != orderByTerm && session.listOrder != orderStored {
+		fmt.Fprintf(stderr, "unknown order %q, use %s or %s\n", session.listOrder, orderByTerm, orderStored)
+		return exitUsage
+	}
+	if session.passThreshold < 0 || session.passThreshold > 100 {
+		fmt.Fprintf(stderr, "pass threshold must be from 0 to 100, got %d\n", session.passThreshold)
+		return exitUsage
+	}
+	session.answerTimeout = time.Duration(timedSeconds) * time.Second
+	flags.Visit(func(f *flag.Flag) {
+		if f.Name == "seed" {
+			flashcards.SetSeed(seed)
+		}
+	})
 
-	var importFilename, exportFilename string
-	flag.StringVar(&importFilename, "import_from", "", "file to import from")
-	flag.StringVar(&exportFilename, "export_to", "", "file to export to")
-	flag.Parse()
+	csvDelimiter, err := parseDelimiter(delimiter)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return exitUsage
+	}
+	flashcards.SetDelimiter(csvDelimiter)
+	flashcards.SetHeader(csvHeader)
+	flashcards.SetComments(csvComments)
+
+	pipeline := importFilename == deck.StdStream && scriptFilename == ""
+	if pipeline && serveAddr == "" && exportFilename == "" {
+		fmt.Fprintln(stderr, "-import_from=- reads the deck from the standard input, which the interactive mode needs too; "+
+			"combine it with -export_to, -serve or -script")
+		return exitUsage
+	}
+
+	if exportFilename == deck.StdStream {
+		lp.out = stderr
+	} else {
+		session.lastExportPath = exportFilename
+	}
+	lineNumber := 0
+	if scriptFilename != "" {
+		script, err := os.Open(scriptFilename)
+		if err != nil {
+			fmt.Fprintln(stderr, err)
+			return exitUsage
+		}
+		defer script.Close()
+		scanner = bufio.NewScanner(script)
+		scanner.Split(func(data []byte, atEOF bool) (int, []byte, error) {
+			advance, token, err := bufio.ScanLines(data, atEOF)
+			if token != nil {
+				lineNumber++
+			}
+			return advance, token, err
+		})
+		ls.scanner = scanner
+		session.assumeYes = true
+	}
 
+	session.loadAutosave(lp, flashcards)
+	flashcards.SetMaxCards(session.maxCards)
+	session.loadMissed(lp)
+	exitCode := exitOK
 	if importFilename != "" {
-		importFlashcardsFromFile(importFilename, lp, flashcards)
+		if _, ok := importFlashcardsFromFile(session, importFilename, lp, flashcards); !ok {
+			exitCode = exitImportFailed
+		}
+	}
+	session.baseline = flashcards.AccuracyByTerm()
+
+	if serveAddr != "" {
+		lp.Say(msgServing, serveAddr)
+		fmt.Fprintln(stderr, serve(serveAddr, session, lp, flashcards))
+		return exitServeFailed
+	}
+	if pipeline {
+		if exitCode != exitOK {
+			return exitCode
+		}
+		if !exportFlashcardsToFile(session, exportFilename, lp, flashcards) {
+			return exitExportFailed
+		}
+		return exitOK
 	}
 
-	action := ""
+	action, lastAction := "", ""
 	for action != "exit" {
-		lp.Println("Input the action (add, remove, import, export, ask, exit, log, hardest card, reset stats):")
-		scanner.Scan()
-		action = scanner.Text()
+		lp.Ask(msgMenu)
+		if !ls.Scan() {
+			break
+		}
+		action = ls.Text()
+		if action == "repeat" {
+			if lastAction == "" {
+				lp.Say(msgNothingToRepeat)
+				lp.Prompt()
+				continue
+			}
+			action = lastAction
+		}
 
+		known := true
 		switch action {
 		case "exit":
 			break
 		case "add":
-			addFlashcard(ls, lp, flashcards)
+			addFlashcard(session, ls, lp, flashcards)
+		case "batch":
+			batchAddFlashcards(session, ls, lp, flashcards)
+		case "copy":
+			copyFlashcard(session, ls, lp, flashcards)
 		case "remove":
-			removeFlashcard(ls, lp, flashcards)
+			removeFlashcard(session, ls, lp, flashcards)
+		case "edit":
+			editFlashcard(session, ls, lp, flashcards)
+		case "swap":
+			swapFlashcard(session, ls, lp, flashcards)
+		case "dedup":
+			deduplicateFlashcards(session, lp, flashcards)
 		case "ask":
-			askFlashcards(ls, lp, flashcards)
+			askFlashcards(session, ls, lp, flashcards, askDefinition)
+		case "practice":
+			practiceFlashcards(session, ls, lp, flashcards)
+		case "ask reverse":
+			askFlashcards(session, ls, lp, flashcards, askTerm)
+		case "ask choice":
+			askFlashcards(session, ls, lp, flashcards, askChoice)
+		case "ask mixed":
+			askFlashcards(session, ls, lp, flashcards, askMixed)
+		case "ask tag":
+			askTaggedFlashcards(session, ls, lp, flashcards)
+		case "ask smart":
+			askSmartFlashcards(session, ls, lp, flashcards)
+		case "ask missed":
+			askMissedFlashcards(session, ls, lp, flashcards)
+		case "ask new":
+			askNewFlashcards(session, ls, lp, flashcards)
+		case "ask plan":
+			askStudyPlan(session, ls, lp, flashcards)
+		case "ask forever":
+			askUntilStopped(session, ls, lp, flashcards)
+		case "ask all":
+			askAllFlashcards(session, ls, lp, flashcards)
+		case "drill":
+			drillFlashcards(session, ls, lp, flashcards)
+		case "peek":
+			peekFlashcard(ls, lp, flashcards)
+		case "import --dry-run":
+			previewImport(ls, lp, flashcards)
+		case "import quizlet":
+			importQuizlet(session, ls, lp, flashcards)
+		case "merge":
+			mergeFiles(session, ls, lp, flashcards)
 		case "import":
-			importFlashcards(ls, lp, flashcards)
+			importFlashcards(session, ls, lp, flashcards)
+		case "save":
+			saveFlashcards(session, lp, flashcards)
 		case "export":
-			exportFlashcards(ls, lp, flashcards)
+			exportFlashcards(session, ls, lp, flashcards)
+		case "export anki":
+			exportAnkiFlashcards(session, ls, lp, flashcards)
+		case "export md":
+			exportMarkdownFlashcards(session, ls, lp, flashcards)
+		case "export reversed":
+			exportReversedFlashcards(session, ls, lp, flashcards)
+		case "split":
+			splitFlashcards(ls, lp, flashcards)
+		case "export stats":
+			exportStats(session, ls, lp, flashcards)
+		case "export hardest":
+			exportHardestFlashcards(session, ls, lp, flashcards)
 		case "log":
-			dumpLogs(ls, lp, logBuilder)
+			dumpLogs(session, ls, lp, logBuilder)
+		case "retag":
+			renameTag(session, ls, lp, flashcards)
+		case "untag":
+			removeTag(session, ls, lp, flashcards)
 		case "hardest card":
 			checkHardestCards(lp, flashcards)
+		case "hardest":
+			printTopHardest(ls, lp, flashcards)
+		case "troublesome":
+			listTroublesomeCards(ls, lp, flashcards)
+		case "stale":
+			listStaleCards(ls, lp, flashcards)
+		case "mastered":
+			listMasteredCards(lp, flashcards)
+		case "clear":
+			clearFlashcards(session, ls, lp, flashcards)
+		case "reset card":
+			resetCard(session, ls, lp, flashcards)
+		case "reset tag":
+			resetTag(session, ls, lp, flashcards)
 		case "reset stats":
-			resetStats(lp, flashcards)
+			resetStats(session, ls, lp, flashcards)
+		case "set mistakes":
+			setMistakes(session, ls, lp, flashcards)
+		case "undo":
+			undo(session, lp, flashcards)
+		case "progress":
+			printProgress(session, lp, flashcards)
+		case "count":
+			countFlashcards(lp, flashcards)
+		case "stats":
+			printCardStats(lp, flashcards)
+		case "recommend":
+			recommendTag(lp, flashcards)
+		case "search":
+			searchFlashcards(ls, lp, flashcards)
+		case "info":
+			printCardInfo(session, ls, lp, flashcards)
+		case "shuffle":
+			shuffleFlashcards(session, lp, flashcards)
+		case "list":
+			listFlashcards(session, lp, flashcards)
+		case "define":
+			defineFlashcard(ls, lp, flashcards)
+		case "term":
+			termOfFlashcard(ls, lp, flashcards)
 		default:
-			lp.Println("Unknown command!")
+			known = false
+			if scriptFilename != "" {
+				lp.Say(msgUnknownScriptCommand, scriptFilename, lineNumber, action)
+			} else {
+				lp.Say(msgUnknownCommand)
+			}
+		}
+		if known && action != "exit" {
+			lastAction = action
 		}
 
-		lp.Println()
+		lp.Prompt()
 	}
 
-	if exportFilename != "" {
-		savedAmount := flashcards.WriteCSV(exportFilename)
-		lp.Printf("%d cards have been saved.\n", savedAmount)
+	session.saveIfAutosave(lp, flashcards)
+	session.saveMissed(lp)
+	if exportFilename != "" && !exportFlashcardsToFile(session, exportFilename, lp, flashcards) {
+		exitCode = exitExportFailed
+	}
+	if flashcards.IsDirty() {
+		lp.Say(msgUnsavedChanges)
 	}
-	lp.Println("Bye bye!")
+	printSessionSummary(lp, session.stats)
+	lp.Say(msgBye)
+	return exitCode
 }
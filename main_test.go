@@ -0,0 +1,1323 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"flashcards/deck"
+)
+
+func runScript(t *testing.T, input string, args ...string) (int, string, string) {
+	t.Helper()
+	var stdout, stderr strings.Builder
+	code := run(append([]string{"-quiet"}, args...), strings.NewReader(input), &stdout, &stderr)
+	return code, stdout.String(), stderr.String()
+}
+
+func TestRunExitCodes(t *testing.T) {
+	dir := t.TempDir()
+	deckFile := filepath.Join(dir, "deck.csv")
+	if err := os.WriteFile(deckFile, []byte("cat,кот\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		input string
+		args  []string
+		want  int
+	}{
+		{"interactive session", "exit\n", nil, exitOK},
+		{"early end of input", "", nil, exitOK},
+		{"unknown flag", "", []string{"-bogus"}, exitUsage},
+		{"unknown language", "", []string{"-lang", "xx"}, exitUsage},
+		{"unknown order", "", []string{"-order", "random"}, exitUsage},
+		{"negative pass threshold", "", []string{"-pass-threshold", "-1"}, exitUsage},
+		{"pass threshold over 100", "", []string{"-pass-threshold", "101"}, exitUsage},
+		{"pass threshold of 100", "exit\n", []string{"-pass-threshold", "100"}, exitOK},
+		{"bad delimiter", "", []string{"-delimiter", "ab"}, exitUsage},
+		{"missing script", "", []string{"-script", filepath.Join(dir, "missing.txt")}, exitUsage},
+		{"stdin import without output", "", []string{"-import_from", "-"}, exitUsage},
+		{"missing import", "exit\n", []string{"-import_from", filepath.Join(dir, "missing.csv")}, exitImportFailed},
+		{"failed export", "exit\n", []string{"-import_from", deckFile, "-export_to", filepath.Join(dir, "missing", "out.csv")}, exitExportFailed},
+		{"pipeline", "a,b\n", []string{"-import_from", "-", "-export_to", "-"}, exitOK},
+		{"failed pipeline import", "\"a,b\n", []string{"-import_from", "-", "-export_to", "-"}, exitImportFailed},
+		{"failed pipeline export", "a,b\n", []string{"-import_from", "-", "-export_to", filepath.Join(dir, "missing", "out.csv")}, exitExportFailed},
+		{"serve on a bad address", "", []string{"-serve", "bad:address:here"}, exitServeFailed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if code, stdout, stderr := runScript(t, tt.input, tt.args...); code != tt.want {
+				t.Errorf("run() = %d, want %d\nstdout:\n%s\nstderr:\n%s", code, tt.want, stdout, stderr)
+			}
+		})
+	}
+}
+
+func TestRunPipeline(t *testing.T) {
+	code, stdout, stderr := runScript(t, "cat,кот,2\ndog,собака\n", "-import_from", "-", "-export_to", "-")
+	if code != exitOK {
+		t.Fatalf("run() = %d, stderr:\n%s", code, stderr)
+	}
+	if want := "cat,кот,2,,,0,0\ndog,собака,0,,,0,0\n"; stdout != want {
+		t.Errorf("stdout = %q, want %q", stdout, want)
+	}
+	if !strings.Contains(stderr, "2 cards have been saved.") {
+		t.Errorf("stderr = %q, want the messages moved off the standard output", stderr)
+	}
+}
+
+func newTestPrinter() (LoggingPrinter, *strings.Builder) {
+	out := &strings.Builder{}
+	return LoggingPrinter{logBuilder: &strings.Builder{}, out: out, quiet: true, messages: messages[defaultLanguage]}, out
+}
+
+func newTestScanner(input string) LoggingScanner {
+	return LoggingScanner{scanner: bufio.NewScanner(strings.NewReader(input)), logBuilder: &strings.Builder{}, closed: new(bool)}
+}
+
+func newTestDeck(pairs ...string) *deck.Flashcards {
+	fc := deck.NewFlashcards()
+	for i := 0; i+1 < len(pairs); i += 2 {
+		fc.CreateOrUpdate(deck.Flashcard{Term: pairs[i], Definition: pairs[i+1]})
+	}
+	return fc
+}
+
+func TestPrintVerdict(t *testing.T) {
+	tests := []struct {
+		name           string
+		correct, asked int
+		want           string
+	}{
+		{"below", 6, 10, "FAILED (60%)\n"},
+		{"at", 7, 10, "PASSED (70%)\n"},
+		{"above", 8, 10, "PASSED (80%)\n"},
+		{"just below", 69, 100, "FAILED (69%)\n"},
+		{"nothing asked", 0, 0, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lp, out := newTestPrinter()
+			printVerdict(lp, tt.correct, tt.asked, 70)
+			if out.String() != tt.want {
+				t.Errorf("printVerdict(%d, %d) printed %q, want %q", tt.correct, tt.asked, out.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestPrintVersion(t *testing.T) {
+	defer func(saved string) { version = saved }(version)
+	version = "1.2.3"
+
+	var out strings.Builder
+	printVersion(&out)
+	if want := "flashcards 1.2.3\n"; out.String() != want {
+		t.Errorf("printVersion() printed %q, want %q", out.String(), want)
+	}
+	if code, stdout, _ := runScript(t, "", "-version"); code != exitOK || stdout != "flashcards 1.2.3\n" {
+		t.Errorf("-version = %d, %q", code, stdout)
+	}
+}
+
+func TestAutosave(t *testing.T) {
+	dir := t.TempDir()
+	saveFile := filepath.Join(dir, "save.csv")
+
+	if code, _, stderr := runScript(t, "add\ncat\nкот\npets\nexit\n", "-autosave", saveFile); code != exitOK {
+		t.Fatalf("first session = %d, stderr:\n%s", code, stderr)
+	}
+	code, stdout, _ := runScript(t, "list\nexit\n", "-autosave", saveFile)
+	if code != exitOK {
+		t.Fatalf("second session = %d", code)
+	}
+	if !strings.Contains(stdout, "1. \"cat\" -> \"кот\" (mistakes: 0), streak: 0\n") {
+		t.Errorf("second session didn't load the saved card:\n%s", stdout)
+	}
+
+	emptyFile := filepath.Join(dir, "empty.csv")
+	if code, _, _ := runScript(t, "count\nexit\n", "-autosave", emptyFile); code != exitOK {
+		t.Fatalf("empty session = %d", code)
+	}
+	if _, err := os.Stat(emptyFile); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Stat() = %v, want no file saved for an empty deck", err)
+	}
+}
+
+func TestMissLogAcrossSessions(t *testing.T) {
+	dir := t.TempDir()
+	deckFile := filepath.Join(dir, "deck.csv")
+	missedFile := filepath.Join(dir, "missed.txt")
+	if err := os.WriteFile(deckFile, []byte("cat,кот\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if code, _, stderr := runScript(t, "ask\n1\nпёс\nexit\n", "-import_from", deckFile, "-missed", missedFile); code != exitOK {
+		t.Fatalf("first session = %d, stderr:\n%s", code, stderr)
+	}
+	if data, _ := os.ReadFile(missedFile); string(data) != "cat\n" {
+		t.Fatalf("miss log after the first session = %q, want %q", data, "cat\n")
+	}
+
+	code, stdout, _ := runScript(t, "ask missed\nкот\nexit\n", "-import_from", deckFile, "-missed", missedFile)
+	if code != exitOK {
+		t.Fatalf("second session = %d", code)
+	}
+	if !strings.Contains(stdout, `Print the definition of "cat":`) || !strings.Contains(stdout, "You got 1 of 1 correct.") {
+		t.Errorf("second session didn't ask the missed card:\n%s", stdout)
+	}
+	if data, _ := os.ReadFile(missedFile); string(data) != "" {
+		t.Errorf("miss log after the second session = %q, want it empty", data)
+	}
+}
+
+func TestMissLogUnreadable(t *testing.T) {
+	code, stdout, _ := runScript(t, "ask missed\nexit\n", "-missed", t.TempDir())
+	if code != exitOK {
+		t.Errorf("run() = %d, want the session to go on", code)
+	}
+	if !strings.Contains(stdout, "No missed cards.") {
+		t.Errorf("stdout = %q, want the menu reached", stdout)
+	}
+}
+
+func TestReverseQuiz(t *testing.T) {
+	tests := []struct {
+		name         string
+		answer       string
+		want         outcome
+		wantMistakes int
+		wantOutput   string
+	}{
+		{"correct", "cat", outcomeCorrect, 0, "Correct!"},
+		{"wrong", "cow", outcomeWrong, 1, `Wrong. The right answer is "cat".`},
+		{"another card", "dog", outcomeWrong, 1, `Wrong. The right answer is "cat", but your answer is correct for a different card.`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fc := newTestDeck("cat", "кот", "dog", "собака")
+			flashcard, _ := fc.GetByTerm("cat")
+			lp, out := newTestPrinter()
+
+			got := checkFlashcard(&Session{}, newTestScanner(tt.answer+"\n"), lp, fc, flashcard, askTerm, true, false)
+			if got != tt.want {
+				t.Errorf("checkFlashcard() = %v, want %v", got, tt.want)
+			}
+			if !strings.Contains(out.String(), `Print the term for "кот":`) || !strings.Contains(out.String(), tt.wantOutput) {
+				t.Errorf("output = %q, want %q", out.String(), tt.wantOutput)
+			}
+			if flashcard, _ := fc.GetByTerm("cat"); flashcard.Mistakes != tt.wantMistakes {
+				t.Errorf("cat has %d mistakes, want %d", flashcard.Mistakes, tt.wantMistakes)
+			}
+			if dog, _ := fc.GetByTerm("dog"); dog.Mistakes != 0 {
+				t.Errorf("dog has %d mistakes, want 0", dog.Mistakes)
+			}
+		})
+	}
+}
+
+func TestAskEmptyDeck(t *testing.T) {
+	lp, out := newTestPrinter()
+	askFlashcards(&Session{}, newTestScanner("1\n"), lp, deck.NewFlashcards(), askDefinition)
+	if want := "There are no cards to ask.\n"; out.String() != want {
+		t.Errorf("output = %q, want %q", out.String(), want)
+	}
+}
+
+func TestAskAllFlashcards(t *testing.T) {
+	fc := newTestDeck("cat", "кот", "dog", "собака", "cow", "корова")
+	session := &Session{listOrder: orderStored}
+	lp, out := newTestPrinter()
+
+	askAllFlashcards(session, newTestScanner("кот\nпёс\nкорова\n"), lp, fc)
+	for _, term := range []string{"cat", "dog", "cow"} {
+		if n := strings.Count(out.String(), `Print the definition of "`+term+`":`); n != 1 {
+			t.Errorf("%q was asked %d times, want once", term, n)
+		}
+	}
+	if !strings.HasSuffix(out.String(), "You got 2 of 3 correct.\n") {
+		t.Errorf("output = %q, want the tally last", out.String())
+	}
+	if session.stats != (SessionStats{Asked: 3, Correct: 2}) {
+		t.Errorf("session stats = %+v", session.stats)
+	}
+	if dog, _ := fc.GetByTerm("dog"); dog.Mistakes != 1 {
+		t.Errorf("dog has %d mistakes, want 1", dog.Mistakes)
+	}
+}
+
+func TestMatchAnswer(t *testing.T) {
+	tests := []struct {
+		expected, got   string
+		strict, lenient bool
+	}{
+		{"кот", "кот", true, true},
+		{"кот", " кот ", false, true},
+		{"Paris", "paris", false, true},
+		{"Paris", "PARIS\t", false, true},
+		{"кот", "кошка", false, false},
+		{"кот", "", false, false},
+		{"line one\nline two", "line one  \n  line two", true, true},
+		{"line one\nline two", "line one\nline three", false, false},
+	}
+	for _, tt := range tests {
+		if got := matchAnswer(tt.expected, tt.got, false); got != tt.strict {
+			t.Errorf("matchAnswer(%q, %q, false) = %v, want %v", tt.expected, tt.got, got, tt.strict)
+		}
+		if got := matchAnswer(tt.expected, tt.got, true); got != tt.lenient {
+			t.Errorf("matchAnswer(%q, %q, true) = %v, want %v", tt.expected, tt.got, got, tt.lenient)
+		}
+	}
+}
+
+func TestUndo(t *testing.T) {
+	fc := newTestDeck()
+	session := &Session{}
+	lp, out := newTestPrinter()
+
+	addFlashcard(session, newTestScanner("cat\nкот\npets\n"), lp, fc)
+	fc.IncrementMistakes("cat")
+	fc.IncrementMistakes("cat")
+	removeFlashcard(session, newTestScanner("cat\n"), lp, fc)
+	if fc.Len() != 0 {
+		t.Fatalf("deck has %d cards after the removal", fc.Len())
+	}
+
+	undo(session, lp, fc)
+	cat, exists := fc.GetByTerm("cat")
+	if !exists || cat.Mistakes != 2 || !cat.HasTag("pets") {
+		t.Errorf("after undoing the removal cat = %+v, %v, want it back with 2 mistakes", cat, exists)
+	}
+	undo(session, lp, fc)
+	if fc.Len() != 0 {
+		t.Errorf("after undoing the add the deck has %d cards", fc.Len())
+	}
+	out.Reset()
+	undo(session, lp, fc)
+	if want := "Nothing to undo.\n"; out.String() != want {
+		t.Errorf("output = %q, want %q", out.String(), want)
+	}
+}
+
+func TestUndoHistoryLimit(t *testing.T) {
+	fc := newTestDeck()
+	session := &Session{}
+	lp, _ := newTestPrinter()
+
+	for i := 0; i < maxUndoHistory+2; i++ {
+		addFlashcard(session, newTestScanner(fmt.Sprintf("term%d\ndefinition%d\n\n", i, i)), lp, fc)
+	}
+	for i := 0; i < maxUndoHistory+2; i++ {
+		undo(session, lp, fc)
+	}
+	if fc.Len() != 2 {
+		t.Errorf("deck has %d cards, want the 2 oldest adds kept", fc.Len())
+	}
+}
+
+func TestDefineAndTermWithTrailingWhitespace(t *testing.T) {
+	fc := newTestDeck("cat", "кот", "big cat ", "тигр")
+
+	tests := []struct {
+		name   string
+		lookup func(LoggingScanner, LoggingPrinter, *deck.Flashcards)
+		input  string
+		want   string
+	}{
+		{"define exact", defineFlashcard, "cat", `The definition of "cat" is "кот".`},
+		{"define trailing space", defineFlashcard, "cat  ", `The definition of "cat" is "кот".`},
+		{"define trailing tab", defineFlashcard, "cat\t", `The definition of "cat" is "кот".`},
+		{"define stored with space", defineFlashcard, "big cat ", `The definition of "big cat" is "тигр".`},
+		{"define missing", defineFlashcard, "dog ", `There is no card "dog ".`},
+		{"term trailing space", termOfFlashcard, "кот ", `The term for "кот" is "cat".`},
+		{"term missing", termOfFlashcard, "пёс", `There is no card with the definition "пёс".`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lp, out := newTestPrinter()
+			tt.lookup(newTestScanner(tt.input+"\n"), lp, fc)
+			if out.String() != tt.want+"\n" {
+				t.Errorf("output = %q, want %q", out.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestDrillFlashcards(t *testing.T) {
+	fc := newTestDeck("cat", "кот", "dog", "собака", "cow", "корова")
+	fc.SetMistakes("cat", 2)
+	lp, out := newTestPrinter()
+
+	drillFlashcards(&Session{}, newTestScanner("пёс\nмышь\nкот\n"), lp, fc)
+	if n := strings.Count(out.String(), `Print the definition of "cat":`); n != 3 {
+		t.Errorf("cat was asked %d times, want 3", n)
+	}
+	if !strings.HasSuffix(out.String(), "You answered 1 cards correctly in 3 attempts.\n") {
+		t.Errorf("output = %q, want the drill result", out.String())
+	}
+	if cat, _ := fc.GetByTerm("cat"); cat.Mistakes != 4 || cat.Correct != 1 {
+		t.Errorf("cat = %+v, want 4 mistakes and 1 correct", cat)
+	}
+}
+
+func TestDrillWithoutMistakes(t *testing.T) {
+	lp, out := newTestPrinter()
+	drillFlashcards(&Session{}, newTestScanner(""), lp, newTestDeck("cat", "кот"))
+	if want := "There are no cards with errors.\n"; out.String() != want {
+		t.Errorf("output = %q, want %q", out.String(), want)
+	}
+}
+
+func TestDrillEndOfInput(t *testing.T) {
+	fc := newTestDeck("cat", "кот")
+	fc.SetMistakes("cat", 1)
+	lp, _ := newTestPrinter()
+
+	drillFlashcards(&Session{}, newTestScanner("пёс\n"), lp, fc)
+	if cat, _ := fc.GetByTerm("cat"); cat.Mistakes != 2 {
+		t.Errorf("cat has %d mistakes, want only the answered question counted", cat.Mistakes)
+	}
+}
+
+func TestTimedAnswer(t *testing.T) {
+	fc := newTestDeck("cat", "кот")
+	flashcard, _ := fc.GetByTerm("cat")
+	session := &Session{answerTimeout: 20 * time.Millisecond}
+	reader, writer := io.Pipe()
+	ls := LoggingScanner{scanner: bufio.NewScanner(reader), logBuilder: &strings.Builder{}, closed: new(bool)}
+	lp, out := newTestPrinter()
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		io.WriteString(writer, "кот\nкот\n")
+		writer.Close()
+	}()
+
+	if got := checkFlashcard(session, ls, lp, fc, flashcard, askDefinition, true, false); got != outcomeWrong {
+		t.Errorf("late answer = %v, want wrong", got)
+	}
+	if !strings.Contains(out.String(), "Time's up!") {
+		t.Errorf("output = %q, want the timeout reported", out.String())
+	}
+	if got := checkFlashcard(session, ls, lp, fc, flashcard, askDefinition, true, false); got != outcomeCorrect {
+		t.Errorf("next answer = %v, want the late answer not carried over", got)
+	}
+	if cat, _ := fc.GetByTerm("cat"); cat.Mistakes != 1 || cat.Correct != 1 {
+		t.Errorf("cat = %+v, want 1 mistake and 1 correct", cat)
+	}
+}
+
+func TestBatchAddFlashcards(t *testing.T) {
+	fc := newTestDeck("cat", "кот")
+	session := &Session{}
+	lp, out := newTestPrinter()
+
+	batchAddFlashcards(session, newTestScanner("dog = собака\ncat=кошка\nno separator\n =пусто\ncow=кот\nbird=птица\n\nignored=x\n"), lp, fc)
+	want := strings.Join([]string{
+		`The card "cat" already exists, skipped.`,
+		`The line "no separator" has no "=", skipped.`,
+		`The line " =пусто" is skipped: the term is empty.`,
+		`The definition "кот" already exists, skipped.`,
+		"2 cards have been added, 4 skipped.",
+	}, "\n") + "\n"
+	if out.String() != want {
+		t.Errorf("output = %q, want %q", out.String(), want)
+	}
+	if got := fmt.Sprint(fc.Len(), session.stats.Added); got != "3 2" {
+		t.Errorf("deck size and added count = %s, want 3 2", got)
+	}
+	if definition, _ := fc.FindDefinitionByTerm("dog"); definition != "собака" {
+		t.Errorf("dog = %q, want the spaces trimmed", definition)
+	}
+}
+
+func TestSetMistakesCommand(t *testing.T) {
+	tests := []struct {
+		name         string
+		input        string
+		wantOutput   string
+		wantMistakes int
+	}{
+		{"valid", "cat\n3\n", "The card \"cat\" now has 3 mistakes.\n", 3},
+		{"zero", "cat\n0\n", "The card \"cat\" now has 0 mistakes.\n", 0},
+		{"invalid then valid", "cat\n-1\nmany\n2\n", "Please enter a non-negative number.\nPlease enter a non-negative number.\nThe card \"cat\" now has 2 mistakes.\n", 2},
+		{"invalid until the end", "cat\nmany\n", "Please enter a non-negative number.\n", 1},
+		{"missing card", "dog\n3\n", "Can't set mistakes for \"dog\": there is no such card.\n", 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fc := newTestDeck("cat", "кот")
+			fc.SetMistakes("cat", 1)
+			lp, out := newTestPrinter()
+
+			setMistakes(&Session{}, newTestScanner(tt.input), lp, fc)
+			if out.String() != tt.wantOutput {
+				t.Errorf("output = %q, want %q", out.String(), tt.wantOutput)
+			}
+			if cat, _ := fc.GetByTerm("cat"); cat.Mistakes != tt.wantMistakes {
+				t.Errorf("cat has %d mistakes, want %d", cat.Mistakes, tt.wantMistakes)
+			}
+		})
+	}
+}
+
+func TestConfirm(t *testing.T) {
+	tests := []struct {
+		input     string
+		assumeYes bool
+		want      bool
+	}{
+		{"y\n", false, true},
+		{"Y\n", false, true},
+		{"n\n", false, false},
+		{"yes\n", false, false},
+		{"\n", false, false},
+		{"", false, false},
+		{"", true, true},
+	}
+	for _, tt := range tests {
+		lp, out := newTestPrinter()
+		if got := confirm(newTestScanner(tt.input), lp, msgResetPrompt, tt.assumeYes); got != tt.want {
+			t.Errorf("confirm(%q, %v) = %v, want %v", tt.input, tt.assumeYes, got, tt.want)
+		}
+		if cancelled := out.String() == "Cancelled.\n"; cancelled == tt.want {
+			t.Errorf("confirm(%q, %v) printed %q", tt.input, tt.assumeYes, out.String())
+		}
+	}
+}
+
+func TestResetStatsConfirmation(t *testing.T) {
+	for _, answer := range []string{"y", "n"} {
+		fc := newTestDeck("cat", "кот")
+		fc.SetMistakes("cat", 3)
+		lp, _ := newTestPrinter()
+
+		resetStats(&Session{}, newTestScanner(answer+"\n"), lp, fc)
+		want := 3
+		if answer == "y" {
+			want = 0
+		}
+		if cat, _ := fc.GetByTerm("cat"); cat.Mistakes != want {
+			t.Errorf("after %q cat has %d mistakes, want %d", answer, cat.Mistakes, want)
+		}
+	}
+}
+
+func TestExportOverwriteConfirmation(t *testing.T) {
+	for _, answer := range []string{"y", "n"} {
+		filename := filepath.Join(t.TempDir(), "deck.csv")
+		if err := os.WriteFile(filename, []byte("old\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		lp, _ := newTestPrinter()
+
+		exportFlashcards(&Session{}, newTestScanner(filename+"\n"+answer+"\n"), lp, newTestDeck("cat", "кот"))
+		want := "old\n"
+		if answer == "y" {
+			want = "cat,кот,0,,,0,0\n"
+		}
+		if data, _ := os.ReadFile(filename); string(data) != want {
+			t.Errorf("after %q the file is %q, want %q", answer, data, want)
+		}
+	}
+}
+
+func TestAddFlashcardRejectsBlankValues(t *testing.T) {
+	fc := newTestDeck()
+	lp, out := newTestPrinter()
+
+	addFlashcard(&Session{}, newTestScanner("\n  \ncat\n\t\nкот\n\n"), lp, fc)
+	want := "The card can't be empty. Try again:\n" +
+		"The card can't be empty. Try again:\n" +
+		"The definition can't be empty. Try again:\n" +
+		"The pair (\"cat\":\"кот\") has been added.\n"
+	if out.String() != want {
+		t.Errorf("output = %q, want %q", out.String(), want)
+	}
+	if definition, _ := fc.FindDefinitionByTerm("cat"); definition != "кот" || fc.Len() != 1 {
+		t.Errorf("deck = %v", fc.All())
+	}
+}
+
+func TestQuiet(t *testing.T) {
+	input := "add\ncat\nкот\n\nexit\n"
+	var loud strings.Builder
+	if code := run(nil, strings.NewReader(input), &loud, io.Discard); code != exitOK {
+		t.Fatalf("run() = %d", code)
+	}
+	_, quiet, _ := runScript(t, input)
+
+	for _, prompt := range []string{"Input the action (add,", "The card:"} {
+		if !strings.Contains(loud.String(), prompt) {
+			t.Errorf("output without -quiet lacks %q", prompt)
+		}
+		if strings.Contains(quiet, prompt) {
+			t.Errorf("output with -quiet has %q", prompt)
+		}
+	}
+	for _, result := range []string{`The pair ("cat":"кот") has been added.`, "This session: 1 added", "Bye bye!"} {
+		if !strings.Contains(loud.String(), result) || !strings.Contains(quiet, result) {
+			t.Errorf("%q is missing from one of the outputs", result)
+		}
+	}
+}
+
+func TestQuietKeepsLog(t *testing.T) {
+	lp, out := newTestPrinter()
+	lp.Ask(msgCardPrompt)
+	lp.Say(msgRemoved)
+
+	if out.String() != "The card has been removed.\n" {
+		t.Errorf("output = %q, want only the result", out.String())
+	}
+	if want := "The card:\nThe card has been removed.\n"; lp.logBuilder.String() != want {
+		t.Errorf("log = %q, want %q", lp.logBuilder.String(), want)
+	}
+}
+
+func TestLevenshtein(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"", "", 0},
+		{"cat", "", 3},
+		{"", "кот", 3},
+		{"cat", "cat", 0},
+		{"cat", "cut", 1},
+		{"cat", "cats", 1},
+		{"kitten", "sitting", 3},
+		{"кошка", "кошки", 1},
+		{"flaw", "lawn", 2},
+	}
+	for _, tt := range tests {
+		if got := levenshtein(tt.a, tt.b); got != tt.want {
+			t.Errorf("levenshtein(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+		if got := levenshtein(tt.b, tt.a); got != tt.want {
+			t.Errorf("levenshtein(%q, %q) = %d, want %d", tt.b, tt.a, got, tt.want)
+		}
+	}
+}
+
+func TestFuzzyAnswers(t *testing.T) {
+	tests := []struct {
+		name     string
+		answer   string
+		distance int
+		want     string
+	}{
+		{"exact", "собака", 2, "Correct!"},
+		{"one typo", "собакa", 1, `Almost! The exact answer is "собака".`},
+		{"at the distance", "сабак", 2, `Almost! The exact answer is "собака".`},
+		{"past the distance", "сабак", 1, `Wrong. The right answer is "собака".`},
+		{"disabled", "собак", 0, `Wrong. The right answer is "собака".`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fc := newTestDeck("dog", "собака")
+			flashcard, _ := fc.GetByTerm("dog")
+			lp, out := newTestPrinter()
+
+			checkFlashcard(&Session{fuzzyDistance: tt.distance}, newTestScanner(tt.answer+"\n"), lp, fc, flashcard, askDefinition, true, false)
+			if !strings.HasSuffix(out.String(), tt.want+"\n") {
+				t.Errorf("output = %q, want %q", out.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestFuzzyAnswerEqualDistance(t *testing.T) {
+	for _, tt := range []struct{ term, definition, other string }{
+		{"cat", "кот", "whale"},
+		{"whale", "кит", "cat"},
+	} {
+		t.Run(tt.term, func(t *testing.T) {
+			fc := newTestDeck("cat", "кот", "whale", "кит")
+			flashcard, _ := fc.GetByTerm(tt.term)
+			lp, out := newTestPrinter()
+
+			got := checkFlashcard(&Session{fuzzyDistance: 1}, newTestScanner("кет\n"), lp, fc, flashcard, askDefinition, true, false)
+			if want := fmt.Sprintf("Almost! The exact answer is %q.\n", tt.definition); got != outcomeCorrect || !strings.HasSuffix(out.String(), want) {
+				t.Errorf("checkFlashcard() = %v, %q, want the asked card's hint %q", got, out.String(), want)
+			}
+			if asked, _ := fc.GetByTerm(tt.term); asked.Correct != 1 || asked.Mistakes != 0 {
+				t.Errorf("%s = %+v, want 1 correct answer", tt.term, asked)
+			}
+			if other, _ := fc.GetByTerm(tt.other); other.Correct != 0 || other.Mistakes != 0 {
+				t.Errorf("%s = %+v, want it untouched", tt.other, other)
+			}
+		})
+	}
+}
+
+func TestSessionSummary(t *testing.T) {
+	input := "add\ncat\nкот\n\nadd\ndog\nсобака\n\nremove\ndog\nask\n2\nкот\nпёс\nexit\n"
+	code, stdout, _ := runScript(t, input)
+	if code != exitOK {
+		t.Fatalf("run() = %d", code)
+	}
+	want := "This session: 2 added, 1 removed, 2 asked (1 correct).\nBye bye!\n"
+	if !strings.HasSuffix(stdout, want) {
+		t.Errorf("stdout ends with %q, want %q", stdout[max(0, len(stdout)-len(want)):], want)
+	}
+}
+
+func TestSessionSummaryIsLogged(t *testing.T) {
+	lp, _ := newTestPrinter()
+	printSessionSummary(lp, SessionStats{Added: 3, Removed: 1, Asked: 12, Correct: 9})
+	if want := "This session: 3 added, 1 removed, 12 asked (9 correct).\n"; lp.logBuilder.String() != want {
+		t.Errorf("log = %q, want %q", lp.logBuilder.String(), want)
+	}
+}
+
+func TestMultilineDefinition(t *testing.T) {
+	fc := newTestDeck()
+	session := &Session{multilineDefinitions: true}
+	lp, _ := newTestPrinter()
+
+	addFlashcard(session, newTestScanner("run\nбежать\nI run every day.\n.\n\n"), lp, fc)
+	flashcard, exists := fc.GetByTerm("run")
+	if !exists || flashcard.Definition != "бежать\nI run every day." {
+		t.Fatalf("run = %+v, %v", flashcard, exists)
+	}
+
+	if got := checkFlashcard(session, newTestScanner("бежать I run every day.\n"), lp, fc, flashcard, askDefinition, true, false); got != outcomeCorrect {
+		t.Errorf("answer on one line = %v, want correct", got)
+	}
+}
+
+func TestPeekFlashcard(t *testing.T) {
+	fc := newTestDeck("cat", "кот")
+	fc.SetMistakes("cat", 2)
+	fc.MarkSaved()
+	lp, out := newTestPrinter()
+
+	peekFlashcard(newTestScanner("\n"), lp, fc)
+	if want := "\"cat\" (press Enter to flip)\n\"cat\" — кот\n"; out.String() != want {
+		t.Errorf("output = %q, want %q", out.String(), want)
+	}
+	if cat, _ := fc.GetByTerm("cat"); cat.Mistakes != 2 || cat.Correct != 0 || !cat.LastSeen.IsZero() || fc.IsDirty() {
+		t.Errorf("cat = %+v, dirty = %v, want it unchanged", cat, fc.IsDirty())
+	}
+}
+
+func TestPeekEmptyDeck(t *testing.T) {
+	lp, out := newTestPrinter()
+	peekFlashcard(newTestScanner(""), lp, newTestDeck())
+	if want := "The deck is empty, there is nothing to peek at.\n"; out.String() != want {
+		t.Errorf("output = %q, want %q", out.String(), want)
+	}
+}
+
+func TestLanguage(t *testing.T) {
+	_, stdout, _ := runScript(t, "count\nexit\n", "-lang", "ru")
+	if !strings.HasSuffix(stdout, "До свидания!\n") {
+		t.Errorf("stdout = %q, want the Russian goodbye", stdout)
+	}
+	if strings.Contains(stdout, "Bye bye!") {
+		t.Errorf("stdout = %q has English text", stdout)
+	}
+}
+
+func TestTranslationsComplete(t *testing.T) {
+	for language, translated := range messages {
+		if len(translated) != len(messages[defaultLanguage]) {
+			t.Errorf("%s has %d messages, %s has %d", language, len(translated), defaultLanguage, len(messages[defaultLanguage]))
+		}
+		for m := range messages[defaultLanguage] {
+			if translated[m] == "" {
+				t.Errorf("%s lacks message %d", language, m)
+			}
+		}
+	}
+}
+
+func TestScript(t *testing.T) {
+	dir := t.TempDir()
+	scriptFile := filepath.Join(dir, "setup.txt")
+	exportFile := filepath.Join(dir, "deck.csv")
+	if err := os.WriteFile(exportFile, []byte("old\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	script := "add\ncat\nкот\npets\nfly\nask\n1\nпёс\nexport\n" + exportFile + "\nexit\n"
+	if err := os.WriteFile(scriptFile, []byte(script), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	code, stdout, _ := runScript(t, "", "-script", scriptFile)
+	if code != exitOK {
+		t.Fatalf("run() = %d", code)
+	}
+	if want := fmt.Sprintf("%s:5: unknown command \"fly\"", scriptFile); !strings.Contains(stdout, want) {
+		t.Errorf("stdout = %q, want %q", stdout, want)
+	}
+	exported := deck.NewFlashcards()
+	if n, err := exported.ReadFile(exportFile); err != nil || n != 1 {
+		t.Fatalf("ReadFile() = %d, %v", n, err)
+	}
+	if cat, _ := exported.GetByTerm("cat"); cat.Mistakes != 1 || !cat.HasTag("pets") || cat.LastSeen.IsZero() {
+		t.Errorf("exported cat = %+v, want it tagged, seen and with 1 mistake", cat)
+	}
+}
+
+func TestHint(t *testing.T) {
+	tests := []struct {
+		name   string
+		input  string
+		want   outcome
+		output string
+	}{
+		{"hint then right", "?\ncat\n", outcomeWrong, "Hint: the term starts with \"c\".\nCorrect, but with a hint, so it counts as a mistake.\n"},
+		{"hint twice then wrong", "?\n?\ncow\n", outcomeWrong, "Hint: the term starts with \"c\".\nHint: the term starts with \"c\".\nWrong. The right answer is \"cat\".\n"},
+		{"no hint", "cat\n", outcomeCorrect, "Correct!\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fc := newTestDeck("cat", "кот")
+			flashcard, _ := fc.GetByTerm("cat")
+			lp, out := newTestPrinter()
+
+			if got := checkFlashcard(&Session{}, newTestScanner(tt.input), lp, fc, flashcard, askTerm, true, false); got != tt.want {
+				t.Errorf("checkFlashcard() = %v, want %v", got, tt.want)
+			}
+			if want := "Print the term for \"кот\":\n" + tt.output; out.String() != want {
+				t.Errorf("output = %q, want %q", out.String(), want)
+			}
+		})
+	}
+}
+
+func TestHintOnlyInReverseMode(t *testing.T) {
+	fc := newTestDeck("cat", "кот")
+	flashcard, _ := fc.GetByTerm("cat")
+	lp, out := newTestPrinter()
+
+	checkFlashcard(&Session{}, newTestScanner("?\n"), lp, fc, flashcard, askDefinition, true, false)
+	if strings.Contains(out.String(), "Hint") {
+		t.Errorf("output = %q, want no hint when asking for the definition", out.String())
+	}
+}
+
+func TestMaxCardsMessage(t *testing.T) {
+	deckFile := filepath.Join(t.TempDir(), "deck.csv")
+	if err := os.WriteFile(deckFile, []byte("a,1\nb,2\nc,3\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	_, stdout, _ := runScript(t, "exit\n", "-import_from", deckFile, "-max-cards", "2")
+	if want := "Stopped at the limit of 2 cards, 1 more were not loaded.\n2 cards have been loaded.\n"; !strings.HasPrefix(stdout, want) {
+		t.Errorf("stdout = %q, want it to start with %q", stdout, want)
+	}
+}
+
+func TestMaxCardsKeepsAutosave(t *testing.T) {
+	saveFile := filepath.Join(t.TempDir(), "save.csv")
+	if err := os.WriteFile(saveFile, []byte("a,1\nb,2\nc,3\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if code, _, _ := runScript(t, "add\nd\n4\n\nexit\n", "-autosave", saveFile, "-max-cards", "2"); code != exitOK {
+		t.Fatalf("run() = %d", code)
+	}
+	saved := deck.NewFlashcards()
+	if n, err := saved.ReadFile(saveFile); err != nil || n != 4 {
+		t.Errorf("ReadFile() = %d, %v, want all 4 cards kept in the autosave", n, err)
+	}
+}
+
+func TestPrintCardInfo(t *testing.T) {
+	lastSeen := time.Date(2024, 3, 4, 5, 6, 0, 0, time.Local)
+	fc := deck.NewFlashcards()
+	fc.CreateOrUpdate(deck.Flashcard{Term: "cat", Definition: "кот", Mistakes: 1, Correct: 4, Streak: 2, Tags: []string{"pets", "nouns"}, LastSeen: lastSeen})
+	fc.CreateOrUpdate(deck.Flashcard{Term: "dog", Definition: "собака"})
+
+	tests := []struct {
+		term string
+		want string
+	}{
+		{"cat", "\"cat\" -> \"кот\" (mistakes: 1)\nCorrect answers: 4\nStreak: 2\nTags: pets, nouns\nLast seen: 2024-03-04 05:06\n"},
+		{"dog", "\"dog\" -> \"собака\" (mistakes: 0)\nCorrect answers: 0\nStreak: 0\nTags: none\nLast seen: never\n"},
+		{"cow", "There is no card \"cow\".\n"},
+	}
+	for _, tt := range tests {
+		lp, out := newTestPrinter()
+		printCardInfo(&Session{}, newTestScanner(tt.term+"\n"), lp, fc)
+		if out.String() != tt.want {
+			t.Errorf("info %s printed %q, want %q", tt.term, out.String(), tt.want)
+		}
+	}
+}
+
+func TestEarlyEndOfInput(t *testing.T) {
+	inputs := []string{
+		"",
+		"add\n",
+		"add\ncat\n",
+		"add\ncat\nкот\n\nask\n3\n",
+		"add\ncat\nкот\n\nask\n3\nкот\n",
+		"add\ncat\nкот\n\nset mistakes\ncat\nmany\n",
+		"add\ncat\nкот\n\nask choice\n1\n",
+		"batch\ncat=кот\n",
+		"reset stats\n",
+	}
+	for _, input := range inputs {
+		done := make(chan string)
+		go func() {
+			code, stdout, _ := runScript(t, input)
+			if code != exitOK {
+				t.Errorf("run(%q) = %d", input, code)
+			}
+			done <- stdout
+		}()
+		select {
+		case stdout := <-done:
+			if !strings.HasSuffix(stdout, "Bye bye!\n") {
+				t.Errorf("run(%q) printed %q, want a goodbye", input, stdout)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("run(%q) hangs", input)
+		}
+	}
+}
+
+func TestStreakInList(t *testing.T) {
+	fc := newTestDeck("cat", "кот")
+	fc.IncrementCorrect("cat")
+	fc.IncrementCorrect("cat")
+	lp, out := newTestPrinter()
+
+	listFlashcards(&Session{listOrder: orderByTerm}, lp, fc)
+	if !strings.Contains(out.String(), "1. \"cat\" -> \"кот\" (mistakes: 0), streak: 2\n") {
+		t.Errorf("output = %q, want the streak listed", out.String())
+	}
+}
+
+func TestCardLineIsTranslated(t *testing.T) {
+	fc := newTestDeck("cat", "кот")
+	fc.IncrementMistakes("cat")
+	session := &Session{listOrder: orderByTerm}
+	lp, out := newTestPrinter()
+	lp.messages = messages["ru"]
+
+	listFlashcards(session, lp, fc)
+	printCardInfo(session, newTestScanner("cat\n"), lp, fc)
+	searchFlashcards(newTestScanner("ко\n"), lp, fc)
+	card := "\"cat\" -> \"кот\" (ошибок: 1)"
+	if !strings.HasPrefix(out.String(), "1. "+card+", подряд: 0\n"+card+"\n") || !strings.HasSuffix(out.String(), "\n"+card+"\n") {
+		t.Errorf("output = %q, want the card printed in Russian", out.String())
+	}
+	if strings.Contains(out.String(), "mistake") {
+		t.Errorf("output = %q, has English text", out.String())
+	}
+}
+
+func TestAskNewFlashcards(t *testing.T) {
+	fc := newTestDeck("cat", "кот", "dog", "собака", "cow", "корова")
+	fc.MarkSeen("dog")
+	lp, out := newTestPrinter()
+
+	askNewFlashcards(&Session{}, newTestScanner("кот\nкорова\n"), lp, fc)
+	if strings.Contains(out.String(), `"dog"`) || !strings.HasSuffix(out.String(), "You got 2 of 2 correct.\n") {
+		t.Errorf("output = %q, want only the unseen cards asked", out.String())
+	}
+
+	out.Reset()
+	askNewFlashcards(&Session{}, newTestScanner(""), lp, fc)
+	if want := "No new cards.\n"; out.String() != want {
+		t.Errorf("output = %q, want %q", out.String(), want)
+	}
+}
+
+func TestClearFlashcards(t *testing.T) {
+	fc := newTestDeck("cat", "кот", "dog", "собака")
+	session := &Session{}
+	lp, out := newTestPrinter()
+
+	clearFlashcards(session, newTestScanner("y\n"), lp, fc)
+	if want := "Deck cleared (2 cards removed).\n"; out.String() != want || fc.Len() != 0 || session.stats.Removed != 2 {
+		t.Errorf("output = %q, Len() = %d, removed = %d", out.String(), fc.Len(), session.stats.Removed)
+	}
+	undo(session, lp, fc)
+	if fc.Len() != 2 {
+		t.Errorf("after undo Len() = %d, want 2", fc.Len())
+	}
+}
+
+func TestDumpLogsToStdout(t *testing.T) {
+	lp, out := newTestPrinter()
+	lp.Say(msgRemoved)
+	lp.Ask(msgCardPrompt)
+	ls := newTestScanner("-\n")
+	ls.logBuilder = lp.logBuilder
+
+	dumpLogs(&Session{}, ls, lp, lp.logBuilder)
+	printed := strings.TrimPrefix(out.String(), "The card has been removed.\n")
+	if printed != lp.logBuilder.String() {
+		t.Errorf("printed log %q, want %q", printed, lp.logBuilder.String())
+	}
+	if !strings.HasPrefix(printed, "The card has been removed.\nThe card:\n") {
+		t.Errorf("printed log %q, want the earlier output in it", printed)
+	}
+}
+
+func TestLogHasActions(t *testing.T) {
+	logFile := filepath.Join(t.TempDir(), "session.log")
+	code, _, _ := runScript(t, "count\nlist\nlog\n"+logFile+"\nexit\n")
+	if code != exitOK {
+		t.Fatalf("run() = %d", code)
+	}
+	data, err := os.ReadFile(logFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, action := range []string{"count\n", "list\n", "log\n"} {
+		if !strings.Contains(string(data), "\n"+action) {
+			t.Errorf("log = %q, want the %q action in it", data, strings.TrimSpace(action))
+		}
+	}
+}
+
+func TestRecommendTag(t *testing.T) {
+	fc := deck.NewFlashcards()
+	fc.CreateOrUpdate(deck.Flashcard{Term: "run", Definition: "бежать", Tags: []string{"verbs"}, Correct: 1, Mistakes: 3})
+	lp, out := newTestPrinter()
+
+	recommendTag(lp, fc)
+	if want := "Your weakest area is \"verbs\" (25% correct). Try \"ask tag\" with it.\n"; out.String() != want {
+		t.Errorf("output = %q, want %q", out.String(), want)
+	}
+}
+
+func TestExportThenSave(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "deck.csv")
+	fc := newTestDeck("cat", "кот")
+	session := &Session{}
+	lp, out := newTestPrinter()
+
+	saveFlashcards(session, lp, fc)
+	if want := "There is no file to save to yet. Use \"export\" first.\n"; out.String() != want {
+		t.Errorf("save without a path printed %q, want %q", out.String(), want)
+	}
+	exportFlashcards(session, newTestScanner(filename+"\n"), lp, fc)
+	fc.CreateOrUpdate(deck.Flashcard{Term: "dog", Definition: "собака"})
+	out.Reset()
+	saveFlashcards(session, lp, fc)
+
+	if want := "2 cards have been saved.\n"; out.String() != want {
+		t.Errorf("output = %q, want %q", out.String(), want)
+	}
+	if data, _ := os.ReadFile(filename); string(data) != "cat,кот,0,,,0,0\ndog,собака,0,,,0,0\n" {
+		t.Errorf("file = %q, want it rewritten with both cards", data)
+	}
+}
+
+func TestQuestionCounter(t *testing.T) {
+	fc := newTestDeck("cat", "кот")
+	lp, out := newTestPrinter()
+	lp.quiet = false
+
+	askFlashcards(&Session{}, newTestScanner("3\nкот\nпёс\nкот\n"), lp, fc, askDefinition)
+	for i := 1; i <= 3; i++ {
+		counter := fmt.Sprintf("Question %d/3:\n", i)
+		if !strings.Contains(out.String(), counter) || !strings.Contains(lp.logBuilder.String(), counter) {
+			t.Errorf("output or log lacks %q", counter)
+		}
+	}
+	first, last := strings.Index(out.String(), "Question 1/3:"), strings.Index(out.String(), "Question 3/3:")
+	if first < 0 || last < first || strings.Contains(out.String(), "Question 4/3:") {
+		t.Errorf("output = %q, want the counter to go up to 3", out.String())
+	}
+}
+
+func TestSharedDefinition(t *testing.T) {
+	tests := []struct {
+		name   string
+		term   string
+		mode   askMode
+		answer string
+		want   string
+	}{
+		{"own definition", "dog", askDefinition, "собака", "Correct!"},
+		{"definition of two others", "cat", askDefinition, "собака", `Wrong. The right answer is "кот", but your definition is correct for "dog", "hound"`},
+		{"synonym term", "dog", askTerm, "hound", "Correct!"},
+		{"own term", "hound", askTerm, "hound", "Correct!"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fc := newTestDeck("dog", "собака", "cat", "кот", "hound", "собака")
+			flashcard, _ := fc.GetByTerm(tt.term)
+			lp, out := newTestPrinter()
+
+			checkFlashcard(&Session{}, newTestScanner(tt.answer+"\n"), lp, fc, flashcard, tt.mode, true, false)
+			if !strings.HasSuffix(out.String(), tt.want+"\n") {
+				t.Errorf("output = %q, want %q", out.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestRemoveByListIndex(t *testing.T) {
+	fc := newTestDeck("dog", "собака", "cat", "кот", "cow", "корова", "3", "три")
+	session := &Session{listOrder: orderByTerm}
+	lp, out := newTestPrinter()
+
+	listFlashcards(session, lp, fc)
+	if !strings.HasPrefix(out.String(), "1. \"3\" -> \"три\" (mistakes: 0), streak: 0\n2. \"cat\"") {
+		t.Fatalf("list printed %q", out.String())
+	}
+
+	removeFlashcard(session, newTestScanner("2\n"), lp, fc)
+	if _, exists := fc.GetByTerm("cat"); exists {
+		t.Error("cat, listed second, is still there")
+	}
+	removeFlashcard(session, newTestScanner("3\n"), lp, fc)
+	if _, exists := fc.GetByTerm("3"); exists || fc.Len() != 2 {
+		t.Errorf("deck has %d cards, want the card named 3 removed before the third listed one", fc.Len())
+	}
+	out.Reset()
+	removeFlashcard(session, newTestScanner("9\n"), lp, fc)
+	if want := "Can't remove \"9\": there is no such card.\n"; out.String() != want || fc.Len() != 2 {
+		t.Errorf("output = %q, want %q", out.String(), want)
+	}
+}
+
+func TestReadPositiveInt(t *testing.T) {
+	tests := []struct {
+		name   string
+		input  string
+		want   int
+		output string
+	}{
+		{"valid", "3\n", 3, ""},
+		{"invalid then valid", "abc\n-2\n 4 \n", 4, "Please enter a positive number.\nPlease enter a positive number.\n"},
+		{"three failures", "0\nx\n-1\n5\n", 0, "Please enter a positive number.\nPlease enter a positive number.\nPlease enter a positive number.\nCancelled.\n"},
+		{"end of input", "x\n", 0, "Please enter a positive number.\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lp, out := newTestPrinter()
+			if got := readPositiveInt(newTestScanner(tt.input), lp, msgHowManyTimes); got != tt.want {
+				t.Errorf("readPositiveInt(%q) = %d, want %d", tt.input, got, tt.want)
+			}
+			if out.String() != tt.output {
+				t.Errorf("output = %q, want %q", out.String(), tt.output)
+			}
+		})
+	}
+}
+
+func TestColoredFeedback(t *testing.T) {
+	for _, color := range []bool{true, false} {
+		t.Run(fmt.Sprint("color=", color), func(t *testing.T) {
+			lp, out := newTestPrinter()
+			lp.color = color
+			lp.SayCorrect(msgCorrect)
+			lp.SayWrong(msgWrong, "кот")
+
+			plain := "Correct!\nWrong. The right answer is \"кот\".\n"
+			if lp.logBuilder.String() != plain {
+				t.Errorf("log = %q, want %q", lp.logBuilder.String(), plain)
+			}
+			want := plain
+			if color {
+				want = colorGreen + "Correct!" + colorReset + "\n" + colorRed + "Wrong. The right answer is \"кот\"." + colorReset + "\n"
+			}
+			if out.String() != want {
+				t.Errorf("output = %q, want %q", out.String(), want)
+			}
+		})
+	}
+}
+
+func TestMergeFilesWithMissingFile(t *testing.T) {
+	dir := t.TempDir()
+	first, second := filepath.Join(dir, "first.csv"), filepath.Join(dir, "second.csv")
+	if err := os.WriteFile(first, []byte("cat,кот,0\ndog,собака,2\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(second, []byte("cow,корова,1\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	missing := filepath.Join(dir, "missing.csv")
+	session := &Session{}
+	lp, out := newTestPrinter()
+	fc := newTestDeck()
+
+	mergeFiles(session, newTestScanner(first+" "+missing+" "+second+"\n"), lp, fc)
+	if fc.Len() != 3 {
+		t.Errorf("deck has %d cards, want 3 from the readable files", fc.Len())
+	}
+	if !strings.Contains(out.String(), "File not found.\n") || !strings.HasSuffix(out.String(), "3 cards have been loaded from 3 files in total.\n") {
+		t.Errorf("output = %q", out.String())
+	}
+	if len(session.undoHistory) != 1 {
+		t.Errorf("undo history has %d entries, want 1", len(session.undoHistory))
+	}
+}
+
+func TestMixedDirection(t *testing.T) {
+	directions := func() string {
+		fc := newTestDeck("cat", "кот", "dog", "собака")
+		fc.SetSeed(7)
+		flashcard, _ := fc.GetByTerm("cat")
+		var directions strings.Builder
+		for i := 0; i < 20; i++ {
+			lp, out := newTestPrinter()
+			checkFlashcard(&Session{}, newTestScanner("cow\n"), lp, fc, flashcard, askMixed, true, false)
+			switch {
+			case strings.HasPrefix(out.String(), `Print the term for "кот":`):
+				directions.WriteByte('t')
+			case strings.HasPrefix(out.String(), `Print the definition of "cat":`):
+				directions.WriteByte('d')
+			default:
+				t.Fatalf("output = %q", out.String())
+			}
+		}
+		if cat, _ := fc.GetByTerm("cat"); cat.Mistakes != 20 {
+			t.Errorf("cat has %d mistakes, want 20", cat.Mistakes)
+		}
+		if dog, _ := fc.GetByTerm("dog"); dog.Mistakes != 0 {
+			t.Errorf("dog has %d mistakes, want 0", dog.Mistakes)
+		}
+		return directions.String()
+	}
+
+	first, second := directions(), directions()
+	if first != second {
+		t.Errorf("the same seed asked %s, then %s", first, second)
+	}
+	if !strings.Contains(first, "t") || !strings.Contains(first, "d") {
+		t.Errorf("asked %s, want both directions", first)
+	}
+}
+
+func TestRepeat(t *testing.T) {
+	code, stdout, _ := runScript(t, "repeat\ncount\nrepeat\nfly\nrepeat\nexit\nrepeat\n")
+	if code != exitOK {
+		t.Fatalf("run() = %d", code)
+	}
+	if !strings.HasPrefix(stdout, "There is no action to repeat yet.\n") {
+		t.Errorf("stdout = %q, want the empty history reported first", stdout)
+	}
+	if n := strings.Count(stdout, "The deck is empty.\n"); n != 3 {
+		t.Errorf("count ran %d times, want 3 with the unknown command not remembered", n)
+	}
+	if n := strings.Count(stdout, "Bye bye!"); n != 1 {
+		t.Errorf("said bye %d times, want exit to end the session once", n)
+	}
+}
+
+func TestRotateLogTwice(t *testing.T) {
+	dir := t.TempDir()
+	logFile := filepath.Join(dir, "session.log")
+
+	for _, content := range []string{"first log\n", "second log\n"} {
+		if err := os.WriteFile(logFile, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := rotateLog(logFile, 5); err != nil {
+			t.Fatalf("rotateLog() = %v", err)
+		}
+	}
+	if _, err := os.Stat(logFile); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Stat() = %v, want the log moved away", err)
+	}
+	rotated, _ := filepath.Glob(logFile + ".*")
+	var contents []string
+	for _, filename := range rotated {
+		data, _ := os.ReadFile(filename)
+		contents = append(contents, string(data))
+	}
+	if fmt.Sprint(contents) != fmt.Sprint([]string{"first log\n", "second log\n"}) {
+		t.Errorf("rotated logs %v hold %q, want both logs kept", rotated, contents)
+	}
+
+	if err := os.WriteFile(logFile, []byte("new\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := rotateLog(logFile, 5); err != nil {
+		t.Fatalf("rotateLog() = %v", err)
+	}
+	if _, err := os.Stat(logFile); err != nil {
+		t.Errorf("Stat() = %v, want a log under the limit left in place", err)
+	}
+}
+
+func TestShuffleOrder(t *testing.T) {
+	shuffled := func() []string {
+		fc := newTestDeck("a", "1", "b", "2", "c", "3", "d", "4", "e", "5")
+		fc.SetSeed(1)
+		session := &Session{listOrder: orderStored}
+		lp, _ := newTestPrinter()
+
+		listFlashcards(session, lp, fc)
+		if got := fmt.Sprint(session.listedTerms); got != "[a b c d e]" {
+			t.Errorf("listed %s before the shuffle, want the insertion order", got)
+		}
+		shuffleFlashcards(session, lp, fc)
+		listFlashcards(session, lp, fc)
+		order := append([]string(nil), session.listedTerms...)
+
+		var answers strings.Builder
+		for range order {
+			answers.WriteString("x\n")
+		}
+		lp, out := newTestPrinter()
+		askAllFlashcards(session, newTestScanner(answers.String()), lp, fc)
+		var asked []string
+		for _, line := range strings.Split(out.String(), "\n") {
+			if term, ok := strings.CutPrefix(line, "Print the definition of \""); ok {
+				asked = append(asked, strings.TrimSuffix(term, "\":"))
+			}
+		}
+		if fmt.Sprint(asked) != fmt.Sprint(order) {
+			t.Errorf("ask all asked %v, want the stored order %v", asked, order)
+		}
+
+		session.listOrder = orderByTerm
+		listFlashcards(session, lp, fc)
+		if got := fmt.Sprint(session.listedTerms); got != "[a b c d e]" {
+			t.Errorf("listed %s by term after the shuffle", got)
+		}
+		return order
+	}
+
+	first, second := shuffled(), shuffled()
+	if fmt.Sprint(first) != fmt.Sprint(second) {
+		t.Errorf("the same seed shuffled to %v, then %v", first, second)
+	}
+	if fmt.Sprint(first) == "[a b c d e]" {
+		t.Error("the shuffle kept the insertion order")
+	}
+}
+
+func TestPracticeLeavesStatsAlone(t *testing.T) {
+	fc := newTestDeck("cat", "кот", "dog", "собака")
+	fc.MarkSaved()
+	session := &Session{}
+	lp, out := newTestPrinter()
+
+	practiceFlashcards(session, newTestScanner("3\nпёс\nпёс\nпёс\n"), lp, fc)
+	if n := strings.Count(out.String(), "Wrong."); n != 3 {
+		t.Fatalf("output = %q, want 3 wrong answers", out.String())
+	}
+	for _, flashcard := range fc.All() {
+		if flashcard.Mistakes != 0 || flashcard.Correct != 0 || flashcard.Streak != 0 || !flashcard.LastSeen.IsZero() {
+			t.Errorf("%s = %+v, want it untouched by practice", flashcard.Term, flashcard)
+		}
+	}
+	if session.stats != (SessionStats{}) || len(session.missedTerms) != 0 {
+		t.Errorf("session = %+v, missed %v, want no answers counted", session.stats, session.missedTerms)
+	}
+	if fc.IsDirty() {
+		t.Error("practice left the deck dirty")
+	}
+}
@@ -0,0 +1,501 @@
+package main
+
+type message int
+
+const (
+	msgMenu message = iota
+	msgUnknownCommand
+	msgNothingToRepeat
+	msgUnknownScriptCommand
+	msgBye
+	msgUnsavedChanges
+	msgSessionSummary
+	msgServing
+	msgFileNamePrompt
+	msgWriteFailed
+	msgReadFailed
+	msgFileNotFound
+	msgSaved
+	msgNoSavePath
+	msgStatsSaved
+	msgPartsPrompt
+	msgSplitSaved
+	msgLoaded
+	msgImportPreview
+	msgFileNamesPrompt
+	msgTermDelimiterPrompt
+	msgRowDelimiterPrompt
+	msgFileLabel
+	msgMergeTotal
+	msgMerged
+	msgSkipped
+	msgClamped
+	msgOverLimit
+	msgNoStdinImport
+	msgCancelled
+	msgOverwritePrompt
+	msgLogSaved
+	msgNothingToUndo
+	msgUndone
+	msgTermIsEmpty
+	msgDefinitionIsEmpty
+	msgNoSuchCardError
+	msgTermExistsError
+	msgDefinitionExistsError
+	msgTooFewFieldsError
+	msgInvalidMistakesError
+	msgInvalidLastSeenError
+	msgInvalidCorrectError
+	msgInvalidStreakError
+	msgCardPrompt
+	msgDefinitionPrompt
+	msgMultilineHint
+	msgTagsPrompt
+	msgEmptyCard
+	msgEmptyDefinition
+	msgCardExists
+	msgDefinitionExists
+	msgPairAdded
+	msgBatchPrompt
+	msgBatchNoSeparator
+	msgBatchInvalid
+	msgBatchCardExists
+	msgBatchDefinitionExists
+	msgBatchAdded
+	msgWhichCard
+	msgWhichDefinition
+	msgNewTermPrompt
+	msgCantCopy
+	msgCopied
+	msgNoDuplicates
+	msgDeduplicated
+	msgRemoved
+	msgCantRemove
+	msgCantEdit
+	msgCantEditError
+	msgCantSwap
+	msgSwapped
+	msgEditTermPrompt
+	msgEditDefinitionPrompt
+	msgEditTagsPrompt
+	msgEdited
+	msgTimesUp
+	msgChoosePrompt
+	msgChoiceRange
+	msgAskDefinitionPrompt
+	msgAskTermPrompt
+	msgCorrect
+	msgCorrectWithHint
+	msgHint
+	msgAlmost
+	msgWrong
+	msgWrongOtherCard
+	msgWrongOtherDefinition
+	msgRightAnswer
+	msgNoCardsToAsk
+	msgWhichTag
+	msgNoTaggedCards
+	msgNewTagPrompt
+	msgInvalidTag
+	msgTagRenamed
+	msgTagRemoved
+	msgHowManyTimes
+	msgQuestionNumber
+	msgPassed
+	msgFailed
+	msgAllResult
+	msgNoNewCards
+	msgNoMissedCards
+	msgStopHint
+	msgPerTagPrompt
+	msgNoTaggedCardsAtAll
+	msgNoCardsWithErrors
+	msgDrillResult
+	msgNothingToPeek
+	msgPeekFront
+	msgPeekBack
+	msgHardestCard
+	msgHowManyCards
+	msgHardestEntry
+	msgMinMistakesPrompt
+	msgNotANumber
+	msgNoTroublesomeCards
+	msgMasteredEntry
+	msgStaleDaysPrompt
+	msgStaleEntry
+	msgStaleNever
+	msgNoStaleCards
+	msgNoMasteredCards
+	msgHardestCards
+	msgNoSuchCard
+	msgNoSuchDefinition
+	msgDefinitionIs
+	msgTermIs
+	msgDeckEmpty
+	msgShuffled
+	msgShuffleOrderNote
+	msgInfoCorrect
+	msgInfoStreak
+	msgInfoTags
+	msgInfoNoTags
+	msgInfoLastSeen
+	msgInfoNeverSeen
+	msgCard
+	msgListEntry
+	msgNeverAsked
+	msgCardStats
+	msgRecommendation
+	msgNothingToRecommend
+	msgSearchPrompt
+	msgNoMatches
+	msgStatsCards
+	msgStatsCardsWithMistakes
+	msgStatsTotalMistakes
+	msgStatsAverageMistakes
+	msgCantSetMistakes
+	msgMistakesPrompt
+	msgNonNegative
+	msgPositive
+	msgMistakesSet
+	msgClearPrompt
+	msgDeckCleared
+	msgResetPrompt
+	msgStatsReset
+	msgCardReset
+	msgTagReset
+	msgNoProgress
+	msgImprovement
+)
+
+const defaultLanguage = "en"
+
+var messages = map[string]map[message]string{
+	"en": {
+		msgMenu:                   "Input the action (add, batch, copy, remove, edit, swap, dedup, import, import --dry-run, import quizlet, merge, save, export, export anki, export md, export reversed, export hardest, export stats, split, ask, practice, ask reverse, ask choice, ask mixed, ask tag, ask smart, ask missed, ask new, ask plan, ask forever, ask all, drill, peek, exit, log, retag, untag, hardest card, hardest, troublesome, mastered, stale, clear, reset stats, reset card, reset tag, set mistakes, undo, progress, count, stats, recommend, search, info, list, shuffle, define, term, repeat):",
+		msgUnknownCommand:         "Unknown command!",
+		msgNothingToRepeat:        "There is no action to repeat yet.",
+		msgUnknownScriptCommand:   "%s:%d: unknown command \"%s\", skipped.",
+		msgBye:                    "Bye bye!",
+		msgUnsavedChanges:         "You have unsaved changes.",
+		msgSessionSummary:         "This session: %d added, %d removed, %d asked (%d correct).",
+		msgServing:                "Serving the deck on %s",
+		msgFileNamePrompt:         "File name:",
+		msgWriteFailed:            "Could not write file: %v",
+		msgReadFailed:             "Could not read file: %v",
+		msgFileNotFound:           "File not found.",
+		msgSaved:                  "%d cards have been saved.",
+		msgStatsSaved:             "The statistics have been saved.",
+		msgPartsPrompt:            "Into how many files?",
+		msgSplitSaved:             "%d files have been written: %s",
+		msgNoSavePath:             "There is no file to save to yet. Use \"export\" first.",
+		msgLoaded:                 "%d cards have been loaded.",
+		msgFileNamesPrompt:        "File names, separated by spaces:",
+		msgTermDelimiterPrompt:    "Delimiter between the term and the definition (empty for %s):",
+		msgRowDelimiterPrompt:     "Delimiter between the cards (empty for %s):",
+		msgFileLabel:              "%s:",
+		msgMergeTotal:             "%d cards have been loaded from %d files in total.",
+		msgImportPreview:          "%d cards would be added, %d conflict by term, %d by definition. The deck is unchanged.",
+		msgMerged:                 "%d cards have been added, %d merged.",
+		msgSkipped:                "Skipped record %d: %s.",
+		msgClamped:                "The card \"%s\" had negative statistics, which have been set to 0.",
+		msgOverLimit:              "Stopped at the limit of %d cards, %d more were not loaded.",
+		msgNoStdinImport:          "Can't import from the standard input in the interactive mode.",
+		msgCancelled:              "Cancelled.",
+		msgOverwritePrompt:        "File exists, overwrite? (y/n)",
+		msgLogSaved:               "The log has been saved.",
+		msgNothingToUndo:          "Nothing to undo.",
+		msgUndone:                 "The last change has been undone.",
+		msgTermIsEmpty:            "the term is empty",
+		msgDefinitionIsEmpty:      "the definition is empty",
+		msgNoSuchCardError:        "there is no such card",
+		msgTermExistsError:        "another card already has this term",
+		msgDefinitionExistsError:  "another card already has this definition",
+		msgTooFewFieldsError:      "the row has fewer than 2 fields",
+		msgInvalidMistakesError:   "the number of mistakes is not a number",
+		msgInvalidLastSeenError:   "the last seen time is not an RFC 3339 time",
+		msgInvalidCorrectError:    "the number of correct answers is not a number",
+		msgInvalidStreakError:     "the streak is not a number",
+		msgCardPrompt:             "The card:",
+		msgDefinitionPrompt:       "The definition of the card",
+		msgMultilineHint:          " (end with a line containing only \"%s\")",
+		msgTagsPrompt:             "The tags of the card (comma-separated, may be empty):",
+		msgEmptyCard:              "The card can't be empty. Try again:",
+		msgEmptyDefinition:        "The definition can't be empty. Try again:",
+		msgCardExists:             "The card \"%s\" already exists. Try again:",
+		msgDefinitionExists:       "The definition \"%s\" already exists. Try again:",
+		msgPairAdded:              "The pair (\"%s\":\"%s\") has been added.",
+		msgBatchPrompt:            "Enter the cards as term=definition, one per line, and an empty line to finish:",
+		msgBatchNoSeparator:       "The line \"%s\" has no \"=\", skipped.",
+		msgBatchInvalid:           "The line \"%s\" is skipped: %s.",
+		msgBatchCardExists:        "The card \"%s\" already exists, skipped.",
+		msgBatchDefinitionExists:  "The definition \"%s\" already exists, skipped.",
+		msgBatchAdded:             "%d cards have been added, %d skipped.",
+		msgWhichCard:              "Which card?",
+		msgWhichDefinition:        "Which definition?",
+		msgNewTermPrompt:          "The new term:",
+		msgCantCopy:               "Can't copy \"%s\": there is no such card.",
+		msgCopied:                 "The card \"%s\" has been copied to \"%s\".",
+		msgNoDuplicates:           "There are no duplicate cards.",
+		msgDeduplicated:           "%d duplicate cards have been merged.",
+		msgRemoved:                "The card has been removed.",
+		msgCantRemove:             "Can't remove \"%s\": there is no such card.",
+		msgCantEdit:               "Can't edit \"%s\": there is no such card.",
+		msgCantEditError:          "Can't edit \"%s\": %s.",
+		msgCantSwap:               "Can't swap \"%s\": %s.",
+		msgSwapped:                "The card has been swapped: \"%s\" — \"%s\".",
+		msgEditTermPrompt:         "The new term (press Enter to keep \"%s\"):",
+		msgEditDefinitionPrompt:   "The new definition (press Enter to keep \"%s\")",
+		msgEditTagsPrompt:         "The new tags (press Enter to keep \"%s\", or \"-\" to remove all):",
+		msgEdited:                 "The card has been edited.",
+		msgTimesUp:                "Time's up! Press Enter to continue.",
+		msgChoosePrompt:           "Choose the definition of \"%s\":",
+		msgChoiceRange:            "Please enter a number from 1 to %d.",
+		msgAskDefinitionPrompt:    "Print the definition of \"%s\":",
+		msgAskTermPrompt:          "Print the term for \"%s\":",
+		msgCorrect:                "Correct!",
+		msgCorrectWithHint:        "Correct, but with a hint, so it counts as a mistake.",
+		msgHint:                   "Hint: the term starts with \"%s\".",
+		msgAlmost:                 "Almost! The exact answer is \"%s\".",
+		msgWrong:                  "Wrong. The right answer is \"%s\".",
+		msgWrongOtherCard:         "Wrong. The right answer is \"%s\", but your answer is correct for a different card.",
+		msgWrongOtherDefinition:   "Wrong. The right answer is \"%s\", but your definition is correct for %s",
+		msgRightAnswer:            "The right answer is \"%s\".",
+		msgNoCardsToAsk:           "There are no cards to ask.",
+		msgWhichTag:               "Which tag?",
+		msgNoTaggedCards:          "There are no cards with the tag \"%s\".",
+		msgNewTagPrompt:           "New name of the tag:",
+		msgInvalidTag:             "Enter a single non-empty tag without commas.",
+		msgTagRenamed:             "The tag \"%s\" has been renamed to \"%s\" on %d cards.",
+		msgTagRemoved:             "The tag \"%s\" has been removed from %d cards.",
+		msgHowManyTimes:           "How many times to ask?",
+		msgQuestionNumber:         "Question %d/%d:",
+		msgPassed:                 "PASSED (%.0f%%)",
+		msgFailed:                 "FAILED (%.0f%%)",
+		msgAllResult:              "You got %d of %d correct.",
+		msgNoNewCards:             "No new cards.",
+		msgNoMissedCards:          "No missed cards.",
+		msgStopHint:               "Type \"%s\" to finish.",
+		msgPerTagPrompt:           "How many cards to ask from each tag?",
+		msgNoTaggedCardsAtAll:     "No cards have tags.",
+		msgNoCardsWithErrors:      "There are no cards with errors.",
+		msgDrillResult:            "You answered %d cards correctly in %d attempts.",
+		msgNothingToPeek:          "The deck is empty, there is nothing to peek at.",
+		msgPeekFront:              "\"%s\" (press Enter to flip)",
+		msgPeekBack:               "\"%s\" — %s",
+		msgHardestCard:            "The hardest card is \"%s\". You have %d errors answering it.",
+		msgHowManyCards:           "How many cards to show?",
+		msgHardestEntry:           "\"%s\": %d errors",
+		msgMinMistakesPrompt:      "Show cards with at least how many mistakes?",
+		msgNotANumber:             "Please enter a number.",
+		msgNoTroublesomeCards:     "No cards have %d or more mistakes.",
+		msgMasteredEntry:          "%s — %s (correct: %d, no mistakes)",
+		msgStaleDaysPrompt:        "Show cards not seen for more than how many days?",
+		msgStaleEntry:             "\"%s\": last seen %s",
+		msgStaleNever:             "\"%s\": never seen",
+		msgNoStaleCards:           "Every card has been seen in the last %d days.",
+		msgNoMasteredCards:        "No cards have been answered without mistakes yet.",
+		msgHardestCards:           "The hardest cards are %s. You have %d errors answering them.",
+		msgNoSuchCard:             "There is no card \"%s\".",
+		msgNoSuchDefinition:       "There is no card with the definition \"%s\".",
+		msgDefinitionIs:           "The definition of \"%s\" is \"%s\".",
+		msgTermIs:                 "The term for \"%s\" is \"%s\".",
+		msgDeckEmpty:              "The deck is empty.",
+		msgShuffled:               "The cards have been shuffled.",
+		msgShuffleOrderNote:       "list sorts the cards by term and ask all shuffles them; run with -order=%s to follow the new order.",
+		msgInfoCorrect:            "Correct answers: %d",
+		msgInfoStreak:             "Streak: %d",
+		msgInfoTags:               "Tags: %s",
+		msgInfoNoTags:             "Tags: none",
+		msgInfoLastSeen:           "Last seen: %s",
+		msgInfoNeverSeen:          "Last seen: never",
+		msgCard:                   "\"%s\" -> \"%s\" (mistakes: %d)",
+		msgListEntry:              "%d. %s, streak: %d",
+		msgNeverAsked:             "%s: never asked",
+		msgCardStats:              "%s: %d correct, %d mistakes (%.0f%% accuracy)",
+		msgRecommendation:         "Your weakest area is \"%s\" (%.0f%% correct). Try \"ask tag\" with it.",
+		msgNothingToRecommend:     "Answer some tagged cards first to get a recommendation.",
+		msgSearchPrompt:           "Search for:",
+		msgNoMatches:              "No matching cards.",
+		msgStatsCards:             "Cards: %d",
+		msgStatsCardsWithMistakes: "Cards with mistakes: %d",
+		msgStatsTotalMistakes:     "Total mistakes: %d",
+		msgStatsAverageMistakes:   "Average mistakes per card: %.1f",
+		msgCantSetMistakes:        "Can't set mistakes for \"%s\": there is no such card.",
+		msgMistakesPrompt:         "The number of mistakes:",
+		msgNonNegative:            "Please enter a non-negative number.",
+		msgPositive:               "Please enter a positive number.",
+		msgMistakesSet:            "The card \"%s\" now has %d mistakes.",
+		msgClearPrompt:            "This will remove every card from the deck. Continue? (y/n)",
+		msgDeckCleared:            "Deck cleared (%d cards removed).",
+		msgResetPrompt:            "This will clear all mistake counts. Continue? (y/n)",
+		msgStatsReset:             "Card statistics have been reset.",
+		msgCardReset:              "The statistics of \"%s\" have been reset.",
+		msgTagReset:               "The statistics of %d cards with the tag \"%s\" have been reset.",
+		msgNoProgress:             "No progress data yet.",
+		msgImprovement:            "\"%s\": accuracy up from %.0f%% to %.0f%%",
+	},
+	"ru": {
+		msgMenu:                   "Введите действие (add, batch, copy, remove, edit, swap, dedup, import, import --dry-run, import quizlet, merge, save, export, export anki, export md, export reversed, export hardest, export stats, split, ask, practice, ask reverse, ask choice, ask mixed, ask tag, ask smart, ask missed, ask new, ask plan, ask forever, ask all, drill, peek, exit, log, retag, untag, hardest card, hardest, troublesome, mastered, stale, clear, reset stats, reset card, reset tag, set mistakes, undo, progress, count, stats, recommend, search, info, list, shuffle, define, term, repeat):",
+		msgUnknownCommand:         "Неизвестная команда!",
+		msgNothingToRepeat:        "Пока нечего повторять.",
+		msgUnknownScriptCommand:   "%s:%d: неизвестная команда \"%s\", пропущена.",
+		msgBye:                    "До свидания!",
+		msgUnsavedChanges:         "Есть несохранённые изменения.",
+		msgSessionSummary:         "За эту сессию: добавлено %d, удалено %d, задано вопросов %d (верно %d).",
+		msgServing:                "Колода доступна по адресу %s",
+		msgFileNamePrompt:         "Имя файла:",
+		msgWriteFailed:            "Не удалось записать файл: %v",
+		msgReadFailed:             "Не удалось прочитать файл: %v",
+		msgFileNotFound:           "Файл не найден.",
+		msgSaved:                  "Сохранено карточек: %d.",
+		msgStatsSaved:             "Статистика сохранена.",
+		msgPartsPrompt:            "На сколько файлов разделить?",
+		msgSplitSaved:             "Записано файлов: %d: %s",
+		msgNoSavePath:             "Пока некуда сохранять. Сначала выполните \"export\".",
+		msgLoaded:                 "Загружено карточек: %d.",
+		msgFileNamesPrompt:        "Имена файлов через пробел:",
+		msgTermDelimiterPrompt:    "Разделитель термина и определения (пусто — %s):",
+		msgRowDelimiterPrompt:     "Разделитель карточек (пусто — %s):",
+		msgFileLabel:              "%s:",
+		msgMergeTotal:             "Всего загружено карточек: %d из файлов: %d.",
+		msgImportPreview:          "Было бы добавлено карточек: %d, совпадений по термину: %d, по определению: %d. Колода не изменена.",
+		msgMerged:                 "Добавлено карточек: %d, объединено: %d.",
+		msgSkipped:                "Пропущена запись %d: %s.",
+		msgClamped:                "У карточки \"%s\" была отрицательная статистика, она заменена на 0.",
+		msgOverLimit:              "Достигнут предел в %d карточек, ещё %d не загружено.",
+		msgNoStdinImport:          "В интерактивном режиме нельзя импортировать из стандартного ввода.",
+		msgCancelled:              "Отменено.",
+		msgOverwritePrompt:        "Файл существует, перезаписать? (y/n)",
+		msgLogSaved:               "Журнал сохранён.",
+		msgNothingToUndo:          "Нечего отменять.",
+		msgUndone:                 "Последнее изменение отменено.",
+		msgTermIsEmpty:            "пустой термин",
+		msgDefinitionIsEmpty:      "пустое определение",
+		msgNoSuchCardError:        "такой карточки нет",
+		msgTermExistsError:        "у другой карточки уже есть такой термин",
+		msgDefinitionExistsError:  "у другой карточки уже есть такое определение",
+		msgTooFewFieldsError:      "в строке меньше 2 полей",
+		msgInvalidMistakesError:   "количество ошибок не является числом",
+		msgInvalidLastSeenError:   "время последнего показа не в формате RFC 3339",
+		msgInvalidCorrectError:    "количество верных ответов не является числом",
+		msgInvalidStreakError:     "серия верных ответов не является числом",
+		msgCardPrompt:             "Карточка:",
+		msgDefinitionPrompt:       "Определение карточки",
+		msgMultilineHint:          " (завершите строкой, содержащей только \"%s\")",
+		msgTagsPrompt:             "Теги карточки (через запятую, можно оставить пустыми):",
+		msgEmptyCard:              "Карточка не может быть пустой. Попробуйте ещё раз:",
+		msgEmptyDefinition:        "Определение не может быть пустым. Попробуйте ещё раз:",
+		msgCardExists:             "Карточка \"%s\" уже существует. Попробуйте ещё раз:",
+		msgDefinitionExists:       "Определение \"%s\" уже существует. Попробуйте ещё раз:",
+		msgPairAdded:              "Пара (\"%s\":\"%s\") добавлена.",
+		msgBatchPrompt:            "Введите карточки в виде термин=определение, по одной в строке, и пустую строку в конце:",
+		msgBatchNoSeparator:       "В строке \"%s\" нет \"=\", пропущена.",
+		msgBatchInvalid:           "Строка \"%s\" пропущена: %s.",
+		msgBatchCardExists:        "Карточка \"%s\" уже существует, пропущена.",
+		msgBatchDefinitionExists:  "Определение \"%s\" уже существует, пропущено.",
+		msgBatchAdded:             "Добавлено карточек: %d, пропущено: %d.",
+		msgWhichCard:              "Какая карточка?",
+		msgWhichDefinition:        "Какое определение?",
+		msgNewTermPrompt:          "Новый термин:",
+		msgCantCopy:               "Нельзя скопировать \"%s\": такой карточки нет.",
+		msgCopied:                 "Карточка \"%s\" скопирована в \"%s\".",
+		msgNoDuplicates:           "Повторяющихся карточек нет.",
+		msgDeduplicated:           "Объединено повторяющихся карточек: %d.",
+		msgRemoved:                "Карточка удалена.",
+		msgCantRemove:             "Нельзя удалить \"%s\": такой карточки нет.",
+		msgCantEdit:               "Нельзя изменить \"%s\": такой карточки нет.",
+		msgCantEditError:          "Нельзя изменить \"%s\": %s.",
+		msgCantSwap:               "Нельзя перевернуть \"%s\": %s.",
+		msgSwapped:                "Карточка перевёрнута: \"%s\" — \"%s\".",
+		msgEditTermPrompt:         "Новый термин (нажмите Enter, чтобы оставить \"%s\"):",
+		msgEditDefinitionPrompt:   "Новое определение (нажмите Enter, чтобы оставить \"%s\")",
+		msgEditTagsPrompt:         "Новые теги (нажмите Enter, чтобы оставить \"%s\", или \"-\", чтобы удалить все):",
+		msgEdited:                 "Карточка изменена.",
+		msgTimesUp:                "Время вышло! Нажмите Enter, чтобы продолжить.",
+		msgChoosePrompt:           "Выберите определение \"%s\":",
+		msgChoiceRange:            "Введите число от 1 до %d.",
+		msgAskDefinitionPrompt:    "Напишите определение \"%s\":",
+		msgAskTermPrompt:          "Напишите термин для \"%s\":",
+		msgCorrect:                "Верно!",
+		msgCorrectWithHint:        "Верно, но с подсказкой, поэтому это считается ошибкой.",
+		msgHint:                   "Подсказка: термин начинается с \"%s\".",
+		msgAlmost:                 "Почти! Точный ответ: \"%s\".",
+		msgWrong:                  "Неверно. Правильный ответ: \"%s\".",
+		msgWrongOtherCard:         "Неверно. Правильный ответ: \"%s\", но ваш ответ подходит к другой карточке.",
+		msgWrongOtherDefinition:   "Неверно. Правильный ответ: \"%s\", но ваше определение подходит к %s",
+		msgRightAnswer:            "Правильный ответ: \"%s\".",
+		msgNoCardsToAsk:           "Нет карточек для вопросов.",
+		msgWhichTag:               "Какой тег?",
+		msgNoTaggedCards:          "Нет карточек с тегом \"%s\".",
+		msgNewTagPrompt:           "Новое название тега:",
+		msgInvalidTag:             "Введите один непустой тег без запятых.",
+		msgTagRenamed:             "Тег \"%s\" переименован в \"%s\" у карточек: %d.",
+		msgTagRemoved:             "Тег \"%s\" удалён у карточек: %d.",
+		msgHowManyTimes:           "Сколько раз спросить?",
+		msgQuestionNumber:         "Вопрос %d/%d:",
+		msgPassed:                 "ЗАЧТЕНО (%.0f%%)",
+		msgFailed:                 "НЕ ЗАЧТЕНО (%.0f%%)",
+		msgAllResult:              "Верных ответов: %d из %d.",
+		msgNoNewCards:             "Новых карточек нет.",
+		msgNoMissedCards:          "Карточек с ошибками нет.",
+		msgStopHint:               "Введите \"%s\", чтобы закончить.",
+		msgPerTagPrompt:           "Сколько карточек спросить из каждого тега?",
+		msgNoTaggedCardsAtAll:     "Ни у одной карточки нет тегов.",
+		msgNoCardsWithErrors:      "Нет карточек с ошибками.",
+		msgDrillResult:            "Вы ответили верно на %d карточек за %d попыток.",
+		msgNothingToPeek:          "Колода пуста, смотреть нечего.",
+		msgPeekFront:              "\"%s\" (нажмите Enter, чтобы перевернуть)",
+		msgPeekBack:               "\"%s\" — %s",
+		msgHardestCard:            "Самая сложная карточка — \"%s\". Ошибок в ответах на неё: %d.",
+		msgHowManyCards:           "Сколько карточек показать?",
+		msgHardestEntry:           "\"%s\": ошибок %d",
+		msgMinMistakesPrompt:      "Показать карточки, у которых ошибок не меньше скольких?",
+		msgNotANumber:             "Введите число.",
+		msgNoTroublesomeCards:     "Нет карточек, у которых ошибок %d или больше.",
+		msgMasteredEntry:          "%s — %s (верно: %d, без ошибок)",
+		msgStaleDaysPrompt:        "Показать карточки, которые не показывались больше скольких дней?",
+		msgStaleEntry:             "\"%s\": последний показ %s",
+		msgStaleNever:             "\"%s\": ни разу не показывалась",
+		msgNoStaleCards:           "Все карточки показывались за последние дни: %d.",
+		msgNoMasteredCards:        "Пока нет карточек, на которые вы ответили без ошибок.",
+		msgHardestCards:           "Самые сложные карточки — %s. Ошибок в ответах на них: %d.",
+		msgNoSuchCard:             "Карточки \"%s\" нет.",
+		msgNoSuchDefinition:       "Нет карточки с определением \"%s\".",
+		msgDefinitionIs:           "Определение \"%s\" — \"%s\".",
+		msgTermIs:                 "Термин для \"%s\" — \"%s\".",
+		msgDeckEmpty:              "Колода пуста.",
+		msgShuffled:               "Карточки перемешаны.",
+		msgShuffleOrderNote:       "list сортирует карточки по термину, а ask all перемешивает их; запустите с -order=%s, чтобы использовать новый порядок.",
+		msgInfoCorrect:            "Верных ответов: %d",
+		msgInfoStreak:             "Верных ответов подряд: %d",
+		msgInfoTags:               "Теги: %s",
+		msgInfoNoTags:             "Теги: нет",
+		msgInfoLastSeen:           "Последний показ: %s",
+		msgInfoNeverSeen:          "Последний показ: никогда",
+		msgCard:                   "\"%s\" -> \"%s\" (ошибок: %d)",
+		msgListEntry:              "%d. %s, подряд: %d",
+		msgNeverAsked:             "%s: ещё не спрашивалась",
+		msgCardStats:              "%s: верно %d, ошибок %d (точность %.0f%%)",
+		msgRecommendation:         "Слабее всего у вас тег \"%s\" (верно %.0f%%). Попробуйте \"ask tag\" с ним.",
+		msgNothingToRecommend:     "Сначала ответьте на несколько карточек с тегами, чтобы получить рекомендацию.",
+		msgSearchPrompt:           "Что искать:",
+		msgNoMatches:              "Подходящих карточек нет.",
+		msgStatsCards:             "Карточек: %d",
+		msgStatsCardsWithMistakes: "Карточек с ошибками: %d",
+		msgStatsTotalMistakes:     "Всего ошибок: %d",
+		msgStatsAverageMistakes:   "В среднем ошибок на карточку: %.1f",
+		msgCantSetMistakes:        "Нельзя задать ошибки для \"%s\": такой карточки нет.",
+		msgMistakesPrompt:         "Количество ошибок:",
+		msgNonNegative:            "Введите неотрицательное число.",
+		msgPositive:               "Введите положительное число.",
+		msgMistakesSet:            "У карточки \"%s\" теперь %d ошибок.",
+		msgClearPrompt:            "Из колоды будут удалены все карточки. Продолжить? (y/n)",
+		msgDeckCleared:            "Колода очищена (удалено карточек: %d).",
+		msgResetPrompt:            "Все счётчики ошибок будут обнулены. Продолжить? (y/n)",
+		msgStatsReset:             "Статистика карточек сброшена.",
+		msgCardReset:              "Статистика карточки \"%s\" сброшена.",
+		msgTagReset:               "Сброшена статистика карточек с тегом \"%[2]s\": %[1]d.",
+		msgNoProgress:             "Пока нет данных о прогрессе.",
+		msgImprovement:            "\"%s\": точность выросла с %.0f%% до %.0f%%",
+	},
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"flashcards/deck"
+)
+
+type server struct {
+	saveMu  sync.Mutex
+	session *Session
+	lp      LoggingPrinter
+	fc      *deck.Flashcards
+}
+
+type answerRequest struct {
+	Term   string `json:"term"`
+	Answer string `json:"answer"`
+}
+
+type answerResponse struct {
+	Correct    bool   `json:"correct"`
+	Definition string `json:"definition"`
+}
+
+func serve(addr string, session *Session, lp LoggingPrinter, fc *deck.Flashcards) error {
+	s := &server{session: session, lp: lp, fc: fc}
+	httpServer := &http.Server{
+		Addr:              addr,
+		Handler:           s.routes(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	return httpServer.ListenAndServe()
+}
+
+func (s *server) routes() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/cards", s.handleCards)
+	mux.HandleFunc("/cards/", s.handleCard)
+	mux.HandleFunc("/ask", s.handleAsk)
+	mux.HandleFunc("/answer", s.handleAnswer)
+	return mux
+}
+
+func (s *server) save() {
+	s.saveMu.Lock()
+	defer s.saveMu.Unlock()
+	s.session.saveIfAutosave(s.lp, s.fc)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]string{"error": message})
+}
+
+func (s *server) handleCards(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		writeJSON(w, http.StatusOK, s.fc.All())
+	case http.MethodPost:
+		var flashcard deck.Flashcard
+		if err := json.NewDecoder(r.Body).Decode(&flashcard); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid card: "+err.Error())
+			return
+		}
+		newFlashcard := deck.Flashcard{
+			Term:       flashcard.Term,
+			Definition: flashcard.Definition,
+			Tags:       flashcard.Tags,
+		}
+		switch err := s.fc.Add(newFlashcard); {
+		case errors.Is(err, deck.ErrTermExists):
+			writeError(w, http.StatusConflict, "the card already exists")
+			return
+		case errors.Is(err, deck.ErrDefinitionExists):
+			writeError(w, http.StatusConflict, "the definition already exists")
+			return
+		case err != nil:
+			writeError(w, http.StatusBadRequest, "invalid card: "+err.Error())
+			return
+		}
+		s.save()
+		writeJSON(w, http.StatusCreated, newFlashcard)
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
+
+func (s *server) handleCard(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	term := strings.TrimPrefix(r.URL.Path, "/cards/")
+	if !s.fc.RemoveByTerm(term) {
+		writeError(w, http.StatusNotFound, "there is no such card")
+		return
+	}
+	s.save()
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *server) handleAsk(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	flashcard, ok := s.fc.GetRandomFc()
+	if !ok {
+		writeError(w, http.StatusNotFound, "there are no cards to ask")
+		return
+	}
+	s.fc.MarkSeen(flashcard.Term)
+	s.save()
+	writeJSON(w, http.StatusOK, map[string]string{"term": flashcard.Term})
+}
+
+func (s *server) handleAnswer(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	var answer answerRequest
+	if err := json.NewDecoder(r.Body).Decode(&answer); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid answer: "+err.Error())
+		return
+	}
+	definition, exists := s.fc.FindDefinitionByTerm(answer.Term)
+	if !exists {
+		writeError(w, http.StatusNotFound, "there is no such card")
+		return
+	}
+
+	correct := matchAnswer(definition, answer.Answer, s.session.lenientAnswers)
+	if correct {
+		s.fc.IncrementCorrect(answer.Term)
+	} else {
+		s.fc.IncrementMistakes(answer.Term)
+	}
+	s.save()
+	writeJSON(w, http.StatusOK, answerResponse{Correct: correct, Definition: definition})
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"flashcards/deck"
+)
+
+func newTestServer(t *testing.T, pairs ...string) (*httptest.Server, *deck.Flashcards) {
+	t.Helper()
+	fc := newTestDeck(pairs...)
+	s := &server{session: &Session{}, lp: LoggingPrinter{logBuilder: &strings.Builder{}, out: &strings.Builder{}}, fc: fc}
+	ts := httptest.NewServer(s.routes())
+	t.Cleanup(ts.Close)
+	return ts, fc
+}
+
+func request(t *testing.T, ts *httptest.Server, method, path, body string) (int, map[string]any) {
+	t.Helper()
+	req, err := http.NewRequest(method, ts.URL+path, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := ts.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	var decoded map[string]any
+	json.NewDecoder(resp.Body).Decode(&decoded)
+	return resp.StatusCode, decoded
+}
+
+func TestServerStatusCodes(t *testing.T) {
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		body   string
+		want   int
+	}{
+		{"list cards", http.MethodGet, "/cards", "", http.StatusOK},
+		{"add a card", http.MethodPost, "/cards", `{"term":"dog","definition":"собака"}`, http.StatusCreated},
+		{"add malformed JSON", http.MethodPost, "/cards", `{"term":`, http.StatusBadRequest},
+		{"add an empty term", http.MethodPost, "/cards", `{"term":" ","definition":"x"}`, http.StatusBadRequest},
+		{"add an existing term", http.MethodPost, "/cards", `{"term":"cat","definition":"x"}`, http.StatusConflict},
+		{"add an existing definition", http.MethodPost, "/cards", `{"term":"x","definition":"кот"}`, http.StatusConflict},
+		{"put cards", http.MethodPut, "/cards", "", http.StatusMethodNotAllowed},
+		{"delete a card", http.MethodDelete, "/cards/cat", "", http.StatusNoContent},
+		{"delete a missing card", http.MethodDelete, "/cards/cow", "", http.StatusNotFound},
+		{"get a card", http.MethodGet, "/cards/cat", "", http.StatusMethodNotAllowed},
+		{"ask", http.MethodPost, "/ask", "", http.StatusOK},
+		{"ask with GET", http.MethodGet, "/ask", "", http.StatusMethodNotAllowed},
+		{"answer", http.MethodPost, "/answer", `{"term":"cat","answer":"кот"}`, http.StatusOK},
+		{"answer malformed JSON", http.MethodPost, "/answer", `nope`, http.StatusBadRequest},
+		{"answer a missing card", http.MethodPost, "/answer", `{"term":"cow","answer":"x"}`, http.StatusNotFound},
+		{"answer with GET", http.MethodGet, "/answer", "", http.StatusMethodNotAllowed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts, _ := newTestServer(t, "cat", "кот")
+			if got, _ := request(t, ts, tt.method, tt.path, tt.body); got != tt.want {
+				t.Errorf("%s %s = %d, want %d", tt.method, tt.path, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestServerAskEmptyDeck(t *testing.T) {
+	ts, _ := newTestServer(t)
+	if got, _ := request(t, ts, http.MethodPost, "/ask", ""); got != http.StatusNotFound {
+		t.Errorf("POST /ask = %d, want %d", got, http.StatusNotFound)
+	}
+}
+
+func TestServerAnswer(t *testing.T) {
+	ts, fc := newTestServer(t, "cat", "кот")
+
+	_, body := request(t, ts, http.MethodPost, "/answer", `{"term":"cat","answer":"кот"}`)
+	if body["correct"] != true || body["definition"] != "кот" {
+		t.Errorf("right answer = %v", body)
+	}
+	_, body = request(t, ts, http.MethodPost, "/answer", `{"term":"cat","answer":"пёс"}`)
+	if body["correct"] != false {
+		t.Errorf("wrong answer = %v", body)
+	}
+	if flashcard, _ := fc.GetByTerm("cat"); flashcard.Correct != 1 || flashcard.Mistakes != 1 {
+		t.Errorf("cat = %+v, want 1 correct and 1 mistake", flashcard)
+	}
+}
+
+func TestServerAutosavesAnswers(t *testing.T) {
+	saveFile := filepath.Join(t.TempDir(), "save.csv")
+	fc := newTestDeck("cat", "кот")
+	s := &server{session: &Session{autosaveFilename: saveFile}, lp: LoggingPrinter{logBuilder: &strings.Builder{}, out: &strings.Builder{}}, fc: fc}
+	ts := httptest.NewServer(s.routes())
+	t.Cleanup(ts.Close)
+
+	request(t, ts, http.MethodPost, "/ask", "")
+	request(t, ts, http.MethodPost, "/answer", `{"term":"cat","answer":"пёс"}`)
+	saved := deck.NewFlashcards()
+	if _, err := saved.ReadFile(saveFile); err != nil {
+		t.Fatalf("ReadFile() = %v", err)
+	}
+	if cat, _ := saved.GetByTerm("cat"); cat.Mistakes != 1 || cat.LastSeen.IsZero() {
+		t.Errorf("saved cat = %+v, want it seen and with 1 mistake", cat)
+	}
+}
+
+func TestServerAddAndDelete(t *testing.T) {
+	ts, fc := newTestServer(t)
+
+	request(t, ts, http.MethodPost, "/cards", `{"term":"dog","definition":"собака","tags":["pets"],"mistakes":5}`)
+	flashcard, exists := fc.GetByTerm("dog")
+	if !exists || flashcard.Mistakes != 0 || !flashcard.HasTag("pets") {
+		t.Errorf("dog = %+v, %v, want a tagged card without mistakes", flashcard, exists)
+	}
+	request(t, ts, http.MethodDelete, "/cards/dog", "")
+	if fc.Len() != 0 {
+		t.Errorf("deck has %d cards after the delete", fc.Len())
+	}
+}
+
+func TestServerConcurrentAdds(t *testing.T) {
+	ts, fc := newTestServer(t)
+
+	statuses := make(chan int)
+	for i := 0; i < 20; i++ {
+		go func() {
+			req, _ := http.NewRequest(http.MethodPost, ts.URL+"/cards", strings.NewReader(`{"term":"dog","definition":"собака"}`))
+			resp, err := ts.Client().Do(req)
+			if err != nil {
+				statuses <- 0
+				return
+			}
+			resp.Body.Close()
+			statuses <- resp.StatusCode
+		}()
+	}
+	created := 0
+	for i := 0; i < 20; i++ {
+		if <-statuses == http.StatusCreated {
+			created++
+		}
+	}
+	if created != 1 || fc.Len() != 1 {
+		t.Errorf("%d requests created the card, deck has %d cards, want 1 and 1", created, fc.Len())
+	}
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"flashcards/deck"
+)
+
+type SessionStats struct {
+	Added   int
+	Removed int
+	Asked   int
+	Correct int
+}
+
+type Session struct {
+	autosaveFilename     string
+	missedFilename       string
+	lenientAnswers       bool
+	fuzzyDistance        int
+	answerTimeout        time.Duration
+	assumeYes            bool
+	mergeOnImport        bool
+	maxCards             int
+	multilineDefinitions bool
+	listOrder            string
+	passThreshold        int
+	requeueChance        float64
+	logMaxSize           int64
+
+	stats          SessionStats
+	missedTerms    []string
+	undoHistory    []deck.Snapshot
+	lastExportPath string
+	listedTerms    []string
+	baseline       map[string]float64
+}
+
+func (session *Session) recordMiss(term string, correct bool) {
+	i := slices.Index(session.missedTerms, term)
+	if correct && i >= 0 {
+		session.missedTerms = slices.Delete(session.missedTerms, i, i+1)
+	} else if !correct && i < 0 {
+		session.missedTerms = append(session.missedTerms, term)
+	}
+}
+
+func (session *Session) loadMissed(lp LoggingPrinter) {
+	if session.missedFilename == "" {
+		return
+	}
+	data, err := os.ReadFile(session.missedFilename)
+	if errors.Is(err, os.ErrNotExist) {
+		return
+	}
+	if err != nil {
+		lp.Say(msgReadFailed, err)
+		return
+	}
+	for _, term := range strings.Split(string(data), "\n") {
+		if term != "" {
+			session.recordMiss(term, false)
+		}
+	}
+}
+
+func (session *Session) saveMissed(lp LoggingPrinter) {
+	if session.missedFilename == "" {
+		return
+	}
+	var data string
+	if len(session.missedTerms) > 0 {
+		data = strings.Join(session.missedTerms, "\n") + "\n"
+	}
+	if err := os.WriteFile(session.missedFilename, []byte(data), 0644); err != nil {
+		lp.Say(msgWriteFailed, err)
+	}
+}
+
+func (session *Session) loadAutosave(lp LoggingPrinter, fc *deck.Flashcards) {
+	if session.autosaveFilename == "" {
+		return
+	}
+	if _, err := os.Stat(session.autosaveFilename); errors.Is(err, os.ErrNotExist) {
+		return
+	}
+	if _, ok := importFlashcardsFromFile(session, session.autosaveFilename, lp, fc); ok {
+		fc.MarkSaved()
+	}
+}
+
+func (session *Session) saveIfAutosave(lp LoggingPrinter, fc *deck.Flashcards) {
+	if session.autosaveFilename == "" || !fc.IsDirty() {
+		return
+	}
+	if fc.Len() == 0 {
+		if _, err := os.Stat(session.autosaveFilename); err != nil {
+			return
+		}
+	}
+	if _, err := fc.WriteFile(session.autosaveFilename); err != nil {
+		lp.Say(msgWriteFailed, err)
+	}
+}
+
+const maxUndoHistory = 10
+
+func (session *Session) rememberForUndo(snapshot deck.Snapshot) {
+	session.undoHistory = append(session.undoHistory, snapshot)
+	if len(session.undoHistory) > maxUndoHistory {
+		session.undoHistory = session.undoHistory[1:]
+	}
+}
+
+func (session *Session) listedTerm(fc *deck.Flashcards, input string) string {
+	if _, exists := fc.GetByTerm(input); exists {
+		return input
+	}
+	n, err := strconv.Atoi(input)
+	if err != nil || n < 1 || n > len(session.listedTerms) {
+		return input
+	}
+	return session.listedTerms[n-1]
+}